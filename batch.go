@@ -0,0 +1,252 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// A Pair is a pair of files to diff as part of a batch.
+type Pair struct {
+	// OldName and NewName label the files in the unified diff.
+	OldName, NewName string
+	// Old and New are the contents of the files.
+	Old, New string
+	// Read, if not nil, reads the contents of the files instead of Old and
+	// New. It is called by the worker that diffs the pair, so that a large
+	// batch need not hold the contents of every file at once.
+	Read func() (old, new string, err error)
+}
+
+// name returns the name by which errors refer to a pair.
+func (p *Pair) name() string {
+	if p.OldName == p.NewName || p.NewName == "" {
+		return p.OldName
+	}
+	if p.OldName == "" {
+		return p.NewName
+	}
+	return p.OldName + " -> " + p.NewName
+}
+
+// A PairResult is the result of diffing a Pair.
+type PairResult struct {
+	// Index is the position of the pair in the batch.
+	Index int
+	Pair  Pair
+	// Edits transform the old file into the new one.
+	Edits []Edit[string]
+	// Unified is the unified diff of the files, or "" if they are equal.
+	Unified string
+	// Err reports why the pair could not be diffed. It is prefixed with the
+	// names of the files.
+	Err error
+}
+
+// BatchOptions controls the diffing of a batch of pairs.
+type BatchOptions struct {
+	// Workers is the number of pairs to diff at once. If zero or negative,
+	// runtime.GOMAXPROCS(0) is used.
+	Workers int
+	// Ordered sends results in the order of the pairs rather than in the
+	// order in which they complete. To bound the results that wait for an
+	// earlier pair, at most four times Workers pairs are in flight at once.
+	Ordered bool
+	// Compute computes the edits between the two files of a pair. If nil,
+	// Lines is used.
+	Compute func(before, after string) []Edit[string]
+}
+
+// DiffPairs diffs a batch of pairs concurrently, and returns their results
+// in the order of the pairs along with the errors of any that failed,
+// joined by errors.Join. If ctx is done before every pair is diffed, the
+// results of the remaining pairs hold ctx.Err().
+func DiffPairs(ctx context.Context, pairs []Pair, opts *BatchOptions) ([]PairResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := make(chan Pair)
+	go func() {
+		defer close(in)
+		for _, p := range pairs {
+			select {
+			case in <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var streamOpts BatchOptions
+	if opts != nil {
+		streamOpts = *opts
+	}
+	streamOpts.Ordered = false // results are placed by index
+
+	results := make([]PairResult, len(pairs))
+	done := make([]bool, len(pairs))
+	for r := range StreamPairs(ctx, in, &streamOpts) {
+		results[r.Index], done[r.Index] = r, true
+	}
+
+	var errs []error
+	for i := range results {
+		if !done[i] {
+			results[i] = PairResult{Index: i, Pair: pairs[i], Err: ctx.Err()}
+			continue
+		}
+		if results[i].Err != nil {
+			errs = append(errs, results[i].Err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return results, errors.Join(errs...)
+}
+
+// StreamPairs diffs the pairs received from pairs concurrently, and sends
+// their results on the returned channel. The channel is closed once pairs
+// is closed and every pair has been diffed, or once ctx is done, in which
+// case pairs that have not been diffed are dropped. The caller must either
+// receive every result or cancel ctx.
+func StreamPairs(ctx context.Context, pairs <-chan Pair, opts *BatchOptions) <-chan PairResult {
+	var o BatchOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.Compute == nil {
+		o.Compute = Lines[string, string]
+	}
+
+	type job struct {
+		index int
+		pair  Pair
+	}
+	jobs := make(chan job)
+	completed := make(chan PairResult)
+	out := make(chan PairResult)
+
+	// In order, the window bounds the pairs in flight: a token is taken
+	// before a pair is dispatched and returned once its result is sent.
+	var window chan struct{}
+	if o.Ordered {
+		window = make(chan struct{}, 4*o.Workers)
+	}
+
+	go func() {
+		defer close(jobs)
+		for index := 0; ctx.Err() == nil; index++ {
+			var p Pair
+			var ok bool
+			select {
+			case p, ok = <-pairs:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+			if window != nil {
+				select {
+				case window <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- job{index, p}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(o.Workers)
+	for i := 0; i < o.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				r := diffPair(j.index, j.pair, o.Compute)
+				select {
+				case completed <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(completed)
+	}()
+
+	go func() {
+		defer close(out)
+		send := func(r PairResult) bool {
+			select {
+			case out <- r:
+				if window != nil {
+					<-window
+				}
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		pending := map[int]PairResult{}
+		next := 0
+		for r := range completed {
+			if !o.Ordered {
+				if !send(r) {
+					return
+				}
+				continue
+			}
+			pending[r.Index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !send(r) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// diffPair diffs a single pair.
+func diffPair(index int, p Pair, compute func(before, after string) []Edit[string]) PairResult {
+	r := PairResult{Index: index, Pair: p}
+	old, new := p.Old, p.New
+	if p.Read != nil {
+		var err error
+		if old, new, err = p.Read(); err != nil {
+			r.Err = fmt.Errorf("%s: %w", p.name(), err)
+			return r
+		}
+	}
+	if old == new {
+		return r
+	}
+	r.Edits = compute(old, new)
+	r.Unified, r.Err = ToUnified(p.OldName, p.NewName, old, r.Edits)
+	if r.Err != nil {
+		r.Err = fmt.Errorf("%s: %w", p.name(), r.Err)
+	}
+	return r
+}
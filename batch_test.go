@@ -0,0 +1,121 @@
+package diff_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/diff"
+)
+
+// batch returns n pairs whose ith pair changes line i%5 of a file.
+func batch(n int) []diff.Pair {
+	pairs := make([]diff.Pair, n)
+	for i := range pairs {
+		old := "a\nb\nc\nd\ne\n"
+		pairs[i] = diff.Pair{
+			OldName: fmt.Sprintf("a/%d", i),
+			NewName: fmt.Sprintf("b/%d", i),
+			Old:     old,
+			New:     strings.Replace(old, string(rune('a'+i%5)), "x", 1),
+		}
+	}
+	return pairs
+}
+
+func TestDiffPairs(t *testing.T) {
+	pairs := batch(100)
+	pairs[3].New = pairs[3].Old
+	pairs[7].Read = func() (string, string, error) { return "", "", errors.New("unreadable") }
+	pairs[8].Read = func() (string, string, error) { return "old\n", "new\n", nil }
+
+	results, err := diff.DiffPairs(context.Background(), pairs, &diff.BatchOptions{Workers: 4})
+	if err == nil || err.Error() != "a/7 -> b/7: unreadable" {
+		t.Errorf("got error %v", err)
+	}
+	for i, r := range results {
+		if r.Index != i || r.Pair.OldName != pairs[i].OldName {
+			t.Fatalf("result %d is for pair %d (%s)", i, r.Index, r.Pair.OldName)
+		}
+		switch i {
+		case 3:
+			if r.Unified != "" || r.Edits != nil || r.Err != nil {
+				t.Errorf("equal files gave %+v", r)
+			}
+		case 7:
+			if r.Err == nil {
+				t.Errorf("unreadable files gave no error")
+			}
+		default:
+			old, new := pairs[i].Old, pairs[i].New
+			if i == 8 {
+				old, new = "old\n", "new\n"
+			}
+			if want := diff.Unified(pairs[i].OldName, pairs[i].NewName, old, new); r.Unified != want || r.Err != nil {
+				t.Errorf("pair %d: got diff %q, error %v; want %q", i, r.Unified, r.Err, want)
+			}
+		}
+	}
+}
+
+func TestDiffPairsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pairs := batch(10)
+	for i := range pairs {
+		i := i
+		pairs[i].Read = func() (string, string, error) {
+			if i == 2 {
+				cancel()
+			}
+			return pairs[i].Old, pairs[i].New, nil
+		}
+	}
+	results, err := diff.DiffPairs(ctx, pairs, &diff.BatchOptions{Workers: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want cancellation", err)
+	}
+	if len(results) != 10 || !errors.Is(results[9].Err, context.Canceled) {
+		t.Errorf("the last result is %+v, want cancellation", results[len(results)-1])
+	}
+}
+
+func TestStreamPairs(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			pairs := make(chan diff.Pair)
+			go func() {
+				defer close(pairs)
+				for i, p := range batch(50) {
+					// Make early pairs slow so that they finish out of order.
+					delay := time.Duration(50-i) * 50 * time.Microsecond
+					old, new := p.Old, p.New
+					p.Read = func() (string, string, error) {
+						time.Sleep(delay)
+						return old, new, nil
+					}
+					pairs <- p
+				}
+			}()
+
+			seen := map[int]bool{}
+			next := 0
+			for r := range diff.StreamPairs(context.Background(), pairs, &diff.BatchOptions{Workers: 8, Ordered: ordered}) {
+				if r.Err != nil || r.Unified == "" {
+					t.Errorf("pair %d: got %+v", r.Index, r)
+				}
+				if ordered && r.Index != next {
+					t.Errorf("got result %d, want %d", r.Index, next)
+				}
+				seen[r.Index] = true
+				next++
+			}
+			if len(seen) != 50 {
+				t.Errorf("got %d results, want 50", len(seen))
+			}
+		})
+	}
+}
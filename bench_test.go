@@ -2,7 +2,9 @@ package diff_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"testing"
 
 	"github.com/pgavlin/diff"
@@ -81,6 +83,24 @@ func benchmarkDiffCore[S text.String](b *testing.B, t1, t2 S) {
 			}
 		})
 
+		b.Run("DifferText", func(b *testing.B) {
+			b.ReportMetric(0, "bytes")
+			b.ReportAllocs()
+			var d diff.Differ[S]
+			for i := 0; i < b.N; i++ {
+				d.Text(t1, t2)
+			}
+		})
+
+		b.Run("DifferLines", func(b *testing.B) {
+			b.ReportMetric(0, "bytes")
+			b.ReportAllocs()
+			var d diff.Differ[S]
+			for i := 0; i < b.N; i++ {
+				d.Lines(t1, t2)
+			}
+		})
+
 		b.Run("Apply", func(b *testing.B) {
 			edits := diff.Text(t1, t2)
 			b.ResetTimer()
@@ -141,3 +161,21 @@ func BenchmarkDiffGlagolitic(b *testing.B) {
 
 	benchmarkDiff(b, d1, d2)
 }
+
+func BenchmarkLinesParallel(b *testing.B) {
+	before, after := largeEdit(rand.New(rand.NewSource(1)), 1000000)
+	b.Run("Lines", func(b *testing.B) {
+		b.ReportMetric(float64(diffSize(b, diff.Lines(before, after))), "bytes")
+		for i := 0; i < b.N; i++ {
+			diff.Lines(before, after)
+		}
+	})
+	for _, workers := range []int{0, 4} {
+		b.Run(fmt.Sprintf("LinesParallel/workers=%d", workers), func(b *testing.B) {
+			b.ReportMetric(float64(diffSize(b, diff.LinesParallel(before, after, workers))), "bytes")
+			for i := 0; i < b.N; i++ {
+				diff.LinesParallel(before, after, workers)
+			}
+		})
+	}
+}
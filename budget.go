@@ -0,0 +1,225 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pgavlin/diff/lcs"
+	"github.com/pgavlin/text"
+)
+
+// A Budget limits the resources that TextContext and LinesContext may spend
+// on a diff. A zero limit imposes no limit.
+type Budget struct {
+	// Time limits the time spent searching for a small diff.
+	Time time.Duration
+	// Memory limits the working memory of the search, in bytes, not
+	// counting the texts themselves. It is checked against an estimate
+	// before the search begins.
+	Memory int
+	// EditDistance sets the depth of the search: the number of inserted
+	// and deleted elements (bytes or runes for TextContext, lines for
+	// LinesContext) that it explores, about half of them from each end of
+	// the texts. A diff that needs more edits exceeds the budget. Unlike
+	// the other fields, it is not only a limit: if it is zero, the search
+	// explores about 30 edits, as Text and Lines do, before it ends early
+	// without exceeding the budget, and a larger value makes the search
+	// deeper, so that it may take more time and memory.
+	EditDistance int
+	// Coarse, if set, makes a diff that exceeds the budget return a valid
+	// but coarser diff rather than ErrBudgetExceeded.
+	Coarse bool
+}
+
+// ErrBudgetExceeded is the error returned by TextContext and LinesContext
+// when a diff exceeds its budget.
+var ErrBudgetExceeded = errors.New("diff exceeds its budget")
+
+// TextContext is like Text, but stops if ctx is done and limits the
+// resources that it spends according to budget, which may be nil.
+//
+// If ctx is done before the diff is computed, TextContext returns
+// ctx.Err(). If the diff exceeds the budget, it returns an error that wraps
+// ErrBudgetExceeded, unless budget.Coarse is set, in which case it returns
+// the edits of a search that ended early, or failing that a single edit
+// that replaces everything between the common prefix and suffix of the
+// texts, and reports that the edits are coarse.
+func TextContext[S1, S2 text.String](ctx context.Context, before S1, after S2, budget *Budget) (edits []Edit[S2], coarse bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if text.Equal(before, after) {
+		return nil, false, nil
+	}
+	s := newSearch(ctx, budget)
+	if isASCII(before) && isASCII(after) {
+		if err := s.checkMemory(0); err != nil {
+			return exceeded(s, coarseEdits(before, after, false), err)
+		}
+		diffs, truncated := lcs.DiffTextBounded(before, after, s.bounds())
+		return finish(s, asciiEdits(diffs, after), truncated)
+	}
+
+	// The runes of the texts take at most four bytes for each of their
+	// bytes.
+	if err := s.checkMemory(4 * (len(before) + len(after))); err != nil {
+		return exceeded(s, coarseEdits(before, after, false), err)
+	}
+	beforeRunes, afterRunes := text.ToRunes(before), text.ToRunes(after)
+	diffs, truncated := lcs.DiffRunesBounded(beforeRunes, afterRunes, s.bounds())
+	return finish(s, runeEdits[S2](diffs, beforeRunes, afterRunes), truncated)
+}
+
+// LinesContext is like Lines, but stops if ctx is done and limits the
+// resources that it spends according to budget, which may be nil. Its
+// results are as for TextContext, except that coarse edits still replace
+// whole lines.
+func LinesContext[S1, S2 text.String](ctx context.Context, before S1, after S2, budget *Budget) (edits []Edit[S2], coarse bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if text.Equal(before, after) {
+		return nil, false, nil
+	}
+	s := newSearch(ctx, budget)
+
+	// Each line takes a string header and an offset.
+	if err := s.checkMemory(32 * (countLines(before) + countLines(after))); err != nil {
+		return exceeded(s, coarseEdits(before, after, true), err)
+	}
+	beforeLines, afterLines := splitLines(before), splitLines(after)
+	diffs, truncated := lcs.DiffLinesBounded(beforeLines, afterLines, s.bounds())
+	return finish(s, lineDiffEdits(diffs, beforeLines, afterLines, after), truncated)
+}
+
+// A search tracks the budget of a diff.
+type search struct {
+	ctx      context.Context
+	budget   Budget
+	limit    int       // the limit of the lcs search from each end, or 0
+	deadline time.Time // the end of the time budget, or zero
+	timedOut bool      // whether the search was stopped by the deadline
+}
+
+func newSearch(ctx context.Context, budget *Budget) *search {
+	s := &search{ctx: ctx}
+	if budget != nil {
+		s.budget = *budget
+	}
+	if s.budget.EditDistance > 0 {
+		s.limit = (s.budget.EditDistance + 1) / 2
+	}
+	if s.budget.Time > 0 {
+		s.deadline = time.Now().Add(s.budget.Time)
+	}
+	return s
+}
+
+// bounds returns the bounds of the lcs search.
+func (s *search) bounds() lcs.Bounds {
+	return lcs.Bounds{Limit: s.limit, Stop: s.stop}
+}
+
+// stop reports whether the lcs search should stop.
+func (s *search) stop() bool {
+	if s.ctx.Err() != nil {
+		return true
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.timedOut = true
+		return true
+	}
+	return false
+}
+
+// checkMemory returns an error if the labels of the lcs search and n more
+// bytes would exceed the memory budget.
+func (s *search) checkMemory(n int) error {
+	if s.budget.Memory <= 0 {
+		return nil
+	}
+	// The search labels each step from each end with an int per diagonal.
+	limit := s.limit
+	if limit == 0 {
+		limit = 15 // the default of the lcs package
+	}
+	if n += 8 * (limit + 1) * (limit + 2); n > s.budget.Memory {
+		return fmt.Errorf("%w: needs about %d bytes of memory", ErrBudgetExceeded, n)
+	}
+	return nil
+}
+
+// finish returns the edits of a search, which are coarse if the search was
+// truncated by the budget.
+func finish[S text.String](s *search, edits []Edit[S], truncated bool) ([]Edit[S], bool, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	switch {
+	case s.timedOut:
+		return exceeded(s, edits, fmt.Errorf("%w: took longer than %v", ErrBudgetExceeded, s.budget.Time))
+	case truncated && s.limit > 0:
+		return exceeded(s, edits, fmt.Errorf("%w: needs more than about %d edits", ErrBudgetExceeded, s.budget.EditDistance))
+	}
+	return edits, false, nil
+}
+
+// exceeded returns coarse edits in place of err, if the budget allows them.
+func exceeded[S text.String](s *search, edits []Edit[S], err error) ([]Edit[S], bool, error) {
+	if !s.budget.Coarse {
+		return nil, false, err
+	}
+	return edits, true, nil
+}
+
+// coarseEdits returns a single edit that replaces everything between the
+// common prefix and suffix of two texts. The edit respects rune boundaries,
+// and if lines is set, replaces whole lines.
+func coarseEdits[S1, S2 text.String](before S1, after S2, lines bool) []Edit[S2] {
+	n := len(before)
+	if len(after) < n {
+		n = len(after)
+	}
+	prefix := 0
+	for prefix < n && before[prefix] == after[prefix] {
+		prefix++
+	}
+	if lines {
+		prefix = text.LastIndexByte(before[:prefix], '\n') + 1
+	} else {
+		for prefix > 0 && (!runeStart(before, prefix) || !runeStart(after, prefix)) {
+			prefix--
+		}
+	}
+
+	suffix := 0
+	for suffix < n-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	if lines {
+		// The suffix must begin a line in both texts. As the suffix is
+		// common, it does after its first newline.
+		start, astart := len(before)-suffix, len(after)-suffix
+		if !(start == prefix || before[start-1] == '\n') || !(astart == prefix || after[astart-1] == '\n') {
+			if i := text.IndexByte(before[start:], '\n'); i >= 0 {
+				suffix -= i + 1
+			} else {
+				suffix = 0
+			}
+		}
+	} else {
+		for suffix > 0 && !utf8.RuneStart(before[len(before)-suffix]) {
+			suffix--
+		}
+	}
+	return []Edit[S2]{{Start: prefix, End: len(before) - suffix, New: after[prefix : len(after)-suffix]}}
+}
+
+// runeStart reports whether offset is at the start of a rune of s, or at
+// its end.
+func runeStart[S text.String](s S, offset int) bool {
+	return offset == len(s) || utf8.RuneStart(s[offset])
+}
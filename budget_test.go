@@ -0,0 +1,107 @@
+package diff_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/diff"
+)
+
+// reversed returns a text of n numbered lines and the text with the lines in
+// reverse order, whose diff is large.
+func reversed(n int) (string, string) {
+	var before, after strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&before, "line %d\n", i)
+		fmt.Fprintf(&after, "line %d\n", n-1-i)
+	}
+	return "header\n" + before.String() + "footer\n", "header\n" + after.String() + "footer\n"
+}
+
+type contextFunc func(ctx context.Context, before, after string, budget *diff.Budget) ([]diff.Edit[string], bool, error)
+
+var contextFuncs = []struct {
+	name    string
+	compute contextFunc
+	plain   func(before, after string) []diff.Edit[string]
+}{
+	{"Text", diff.TextContext[string, string], diff.Text[string, string]},
+	{"Lines", diff.LinesContext[string, string], diff.Lines[string, string]},
+}
+
+func TestContextNoBudget(t *testing.T) {
+	tests := []struct{ before, after string }{
+		{"", ""},
+		{"a\nb\nc\n", "a\nB\nc\n"},
+		{"héllo\nwörld\n", "hello\nwörld!\n"},
+	}
+	// Random short texts, which Text diffs by a bit-parallel search.
+	rand.Seed(1)
+	for i := 0; i < 500; i++ {
+		tests = append(tests,
+			struct{ before, after string }{randstr("abc\n", rand.Intn(40)), randstr("abcd\n", rand.Intn(40))},
+			struct{ before, after string }{randstr("aöc\n", rand.Intn(40)), randstr("aöcd\n", rand.Intn(40))})
+	}
+	for _, f := range contextFuncs {
+		for _, tc := range tests {
+			edits, coarse, err := f.compute(context.Background(), tc.before, tc.after, nil)
+			if err != nil || coarse {
+				t.Errorf("%s(%q, %q): got coarse=%v, error %v", f.name, tc.before, tc.after, coarse, err)
+			}
+			if want := f.plain(tc.before, tc.after); (len(edits) != 0 || len(want) != 0) && !reflect.DeepEqual(edits, want) {
+				t.Errorf("%s(%q, %q): got edits %v, want %v", f.name, tc.before, tc.after, edits, want)
+			}
+		}
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before, after := reversed(10)
+	for _, f := range contextFuncs {
+		if _, _, err := f.compute(ctx, before, after, &diff.Budget{Coarse: true}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: got error %v, want cancellation", f.name, err)
+		}
+	}
+}
+
+func TestContextBudget(t *testing.T) {
+	before, after := reversed(100)
+	for _, f := range contextFuncs {
+		for _, budget := range []diff.Budget{
+			{EditDistance: 4},
+			{Memory: 100},
+			{Time: time.Nanosecond},
+		} {
+			t.Run(fmt.Sprintf("%s/%+v", f.name, budget), func(t *testing.T) {
+				if _, _, err := f.compute(context.Background(), before, after, &budget); !errors.Is(err, diff.ErrBudgetExceeded) {
+					t.Fatalf("got error %v, want ErrBudgetExceeded", err)
+				}
+
+				budget.Coarse = true
+				edits, coarse, err := f.compute(context.Background(), before, after, &budget)
+				if err != nil || !coarse {
+					t.Fatalf("got coarse=%v, error %v", coarse, err)
+				}
+				got, err := diff.Apply(before, edits)
+				if err != nil || got != after {
+					t.Fatalf("applying the coarse edits gave %q, %v", got, err)
+				}
+				if f.name == "Lines" {
+					for _, e := range edits {
+						if e.Start > 0 && before[e.Start-1] != '\n' || e.End > 0 && before[e.End-1] != '\n' {
+							t.Errorf("edit %v does not replace whole lines", e)
+						}
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/lcs"
+)
+
+// A comparer compares files and directories and accumulates the exit
+// status.
+type comparer struct {
+	opts           *options
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	status         int
+}
+
+// trouble reports err and records that the comparison failed.
+func (c *comparer) trouble(err error) {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		msg := pathErr.Err.Error()
+		if msg != "" {
+			msg = strings.ToUpper(msg[:1]) + msg[1:]
+		}
+		err = fmt.Errorf("%s: %s", pathErr.Path, msg)
+	}
+	fmt.Fprintf(c.stderr, "diff: %v\n", err)
+	c.status = exitTrouble
+}
+
+// differ records that the inputs differ.
+func (c *comparer) differ() {
+	if c.status < exitDiffer {
+		c.status = exitDiffer
+	}
+}
+
+// A file is one side of a comparison.
+type file struct {
+	name    string
+	content []byte
+	modTime time.Time
+}
+
+// compare compares the operands a and b and returns the exit status.
+func (c *comparer) compare(a, b string) int {
+	aInfo, aErr := c.stat(a)
+	bInfo, bErr := c.stat(b)
+	switch {
+	case aErr != nil && !(c.opts.newFile && bErr == nil && errors.Is(aErr, fs.ErrNotExist)):
+		c.trouble(aErr)
+	case bErr != nil && !(c.opts.newFile && aErr == nil && errors.Is(bErr, fs.ErrNotExist)):
+		c.trouble(bErr)
+	case aInfo != nil && aInfo.IsDir() && bInfo != nil && bInfo.IsDir():
+		c.compareDirs(a, b)
+	default:
+		// Compare a file with the file of the same name in a directory.
+		if aInfo != nil && aInfo.IsDir() {
+			if b == "-" {
+				c.trouble(fmt.Errorf("cannot compare '-' to a directory"))
+				return c.status
+			}
+			a = filepath.Join(a, filepath.Base(b))
+		} else if bInfo != nil && bInfo.IsDir() {
+			if a == "-" {
+				c.trouble(fmt.Errorf("cannot compare '-' to a directory"))
+				return c.status
+			}
+			b = filepath.Join(b, filepath.Base(a))
+		}
+		fa, err := c.read(a, aErr != nil)
+		if err != nil {
+			c.trouble(err)
+			return c.status
+		}
+		fb, err := c.read(b, bErr != nil)
+		if err != nil {
+			c.trouble(err)
+			return c.status
+		}
+		c.compareFiles(fa, fb, "")
+	}
+	return c.status
+}
+
+// stat returns information about the named operand, or nil for standard
+// input.
+func (c *comparer) stat(name string) (fs.FileInfo, error) {
+	if name == "-" {
+		return nil, nil
+	}
+	return os.Stat(name)
+}
+
+// read reads the named file, which is treated as empty if missing is set.
+func (c *comparer) read(name string, missing bool) (*file, error) {
+	switch {
+	case missing:
+		return &file{name: name, modTime: time.Unix(0, 0)}, nil
+	case name == "-":
+		content, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return nil, err
+		}
+		return &file{name: name, content: content, modTime: time.Now()}, nil
+	default:
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return &file{name: name, content: content, modTime: info.ModTime()}, nil
+	}
+}
+
+// stamp returns the named file with the given content, which has already
+// been read, and the file's modification time.
+func (c *comparer) stamp(name, content string, missing bool) (*file, error) {
+	if missing {
+		return &file{name: name, modTime: time.Unix(0, 0)}, nil
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{name: name, content: []byte(content), modTime: info.ModTime()}, nil
+}
+
+// compareDirs compares the directories a and b, recursively if -r is set.
+func (c *comparer) compareDirs(a, b string) {
+	if c.opts.recursive {
+		c.compareTrees(a, b)
+		return
+	}
+
+	aEntries, err := os.ReadDir(a)
+	if err != nil {
+		c.trouble(err)
+		return
+	}
+	bEntries, err := os.ReadDir(b)
+	if err != nil {
+		c.trouble(err)
+		return
+	}
+	entries := map[string][2]fs.DirEntry{}
+	for _, e := range aEntries {
+		entries[e.Name()] = [2]fs.DirEntry{e, nil}
+	}
+	for _, e := range bEntries {
+		pair := entries[e.Name()]
+		pair[1] = e
+		entries[e.Name()] = pair
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ae, be := entries[name][0], entries[name][1]
+		pa, pb := filepath.Join(a, name), filepath.Join(b, name)
+		switch {
+		case ae == nil && !c.opts.newFile:
+			fmt.Fprintf(c.stdout, "Only in %s: %s\n", b, name)
+			c.differ()
+		case be == nil && !c.opts.newFile:
+			fmt.Fprintf(c.stdout, "Only in %s: %s\n", a, name)
+			c.differ()
+		case (ae == nil || ae.IsDir()) && (be == nil || be.IsDir()):
+			// With -N, a missing directory is treated as empty.
+			fmt.Fprintf(c.stdout, "Common subdirectories: %s and %s\n", pa, pb)
+		case ae != nil && be != nil && ae.IsDir() != be.IsDir():
+			c.typeChanged(pa, pb, ae.IsDir())
+		default:
+			fa, err := c.read(pa, ae == nil)
+			if err != nil {
+				c.trouble(err)
+				continue
+			}
+			fb, err := c.read(pb, be == nil)
+			if err != nil {
+				c.trouble(err)
+				continue
+			}
+			c.compareFiles(fa, fb, c.command(pa, pb))
+		}
+	}
+}
+
+// compareTrees compares the directories a and b recursively.
+func (c *comparer) compareTrees(a, b string) {
+	changes, err := diff.DiffFS(os.DirFS(a), os.DirFS(b), &diff.TreeOptions{Compute: c.edits})
+	if err != nil {
+		c.trouble(err)
+		return
+	}
+
+	var typeChanged []string      // paths that are a file on one side and a directory on the other
+	reported := map[string]bool{} // directories reported as "Only in"
+	for _, change := range changes {
+		if under(change.Path, typeChanged) {
+			continue
+		}
+		pa, pb := filepath.Join(a, filepath.FromSlash(change.Path)), filepath.Join(b, filepath.FromSlash(change.Path))
+		switch change.Kind {
+		case diff.TypeChanged:
+			typeChanged = append(typeChanged, change.Path)
+			c.typeChanged(pa, pb, change.OldIsDir)
+			continue
+		case diff.Added, diff.Removed:
+			if !c.opts.newFile {
+				root, other := b, a
+				if change.Kind == diff.Removed {
+					root, other = a, b
+				}
+				// Report the outermost directory that is missing from the
+				// other tree.
+				p := missingAncestor(other, change.Path)
+				if !reported[p] {
+					reported[p] = true
+					dir, name := path.Split(p)
+					fmt.Fprintf(c.stdout, "Only in %s: %s\n", filepath.Join(root, filepath.FromSlash(dir)), name)
+					c.differ()
+				}
+				continue
+			}
+		}
+
+		// DiffFS has already read and compared the files.
+		fa, err := c.stamp(pa, change.Old, change.Kind == diff.Added)
+		if err != nil {
+			c.trouble(err)
+			continue
+		}
+		fb, err := c.stamp(pb, change.New, change.Kind == diff.Removed)
+		if err != nil {
+			c.trouble(err)
+			continue
+		}
+		if !change.Binary && len(change.Edits) == 0 {
+			continue // differences were ignored
+		}
+		c.report(fa, fb, change.Binary, change.Edits, c.command(pa, pb))
+	}
+}
+
+// under reports whether p lies below any of dirs.
+func under(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// missingAncestor returns the shortest prefix of the slash-separated path p
+// that does not exist under root.
+func missingAncestor(root, p string) string {
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(p[:i]))); err != nil {
+				return p[:i]
+			}
+		}
+	}
+	return p
+}
+
+// typeChanged reports that a is a directory and b a file, or vice versa.
+func (c *comparer) typeChanged(a, b string, aIsDir bool) {
+	describe := func(name string, isDir bool) string {
+		if isDir {
+			return "directory"
+		}
+		if info, err := os.Stat(name); err == nil && info.Size() == 0 {
+			return "regular empty file"
+		}
+		return "regular file"
+	}
+	fmt.Fprintf(c.stdout, "File %s is a %s while file %s is a %s\n", c.name(0, a), describe(a, aIsDir), c.name(1, b), describe(b, !aIsDir))
+	c.differ()
+}
+
+// command returns the line that introduces the comparison of a and b within
+// a directory comparison.
+func (c *comparer) command(a, b string) string {
+	return strings.Join(append(append([]string{"diff"}, c.opts.flags...), c.name(0, a), c.name(1, b)), " ") + "\n"
+}
+
+// name returns the name by which the i'th file is reported: the label given
+// with --label, if any, or its path.
+func (c *comparer) name(i int, path string) string {
+	if i < len(c.opts.labels) {
+		return c.opts.labels[i]
+	}
+	return path
+}
+
+// compareFiles compares the files a and b. If they differ, it writes header
+// followed by their differences.
+func (c *comparer) compareFiles(a, b *file, header string) {
+	if bytes.Equal(a.content, b.content) {
+		return
+	}
+	binary := diff.IsBinary(a.content) || diff.IsBinary(b.content)
+	var edits []diff.Edit[string]
+	if !binary {
+		edits = c.edits(string(a.content), string(b.content))
+		if len(edits) == 0 {
+			return // differences were ignored
+		}
+	}
+	c.report(a, b, binary, edits, header)
+}
+
+// report writes header followed by the differences between a and b, which
+// are the edits unless either file is binary.
+func (c *comparer) report(a, b *file, binary bool, edits []diff.Edit[string], header string) {
+	c.differ()
+
+	switch {
+	case c.opts.brief:
+		fmt.Fprintf(c.stdout, "Files %s and %s differ\n", c.name(0, a.name), c.name(1, b.name))
+	case binary:
+		fmt.Fprintf(c.stdout, "Binary files %s and %s differ\n", c.name(0, a.name), c.name(1, b.name))
+	default:
+		out, err := c.format(a, b, edits)
+		if err != nil {
+			c.trouble(err)
+			return
+		}
+		io.WriteString(c.stdout, header+out)
+	}
+}
+
+// format renders the differences between a and b in the selected format.
+func (c *comparer) format(a, b *file, edits []diff.Edit[string]) (string, error) {
+	var out string
+	var err error
+	switch c.opts.format {
+	case formatUnified:
+		out, err = diff.ToUnifiedContext(c.label(0, a), c.label(1, b), string(a.content), edits, c.opts.context)
+	case formatContext:
+		out, err = diff.ToContext(c.label(0, a), c.label(1, b), string(a.content), edits, c.opts.context)
+	case formatSideBySide:
+		return sideBySide(string(a.content), string(b.content), edits, c.opts.width), nil
+	default:
+		out = normal(string(a.content), edits)
+	}
+	if err != nil {
+		return "", err
+	}
+	if c.opts.color {
+		out = colorize(out, c.opts.format)
+	}
+	return out, nil
+}
+
+// label returns the label of the i'th file in a header: the label given
+// with --label, or the file's name and modification time.
+func (c *comparer) label(i int, f *file) string {
+	if i < len(c.opts.labels) {
+		return c.opts.labels[i]
+	}
+	if c.opts.format == formatContext {
+		return f.name + "\t" + f.modTime.Format("Mon Jan _2 15:04:05 2006")
+	}
+	return f.name + "\t" + f.modTime.Format("2006-01-02 15:04:05.000000000 -0700")
+}
+
+// edits returns the line edits that transform a into b, ignoring the
+// differences selected by -w and -i.
+func (c *comparer) edits(a, b string) []diff.Edit[string] {
+	if !c.opts.ignoreSpace && !c.opts.ignoreCase {
+		return diff.Lines(a, b)
+	}
+
+	aLines, bLines := splitLines(a), splitLines(b)
+	diffs := lcs.DiffSlices(c.keys(aLines), c.keys(bLines))
+	aOffsets, bOffsets := lineOffsets(aLines), lineOffsets(bLines)
+	edits := make([]diff.Edit[string], len(diffs))
+	for i, d := range diffs {
+		edits[i] = diff.Edit[string]{
+			Start: aOffsets[d.Start],
+			End:   aOffsets[d.End],
+			New:   b[bOffsets[d.ReplStart]:bOffsets[d.ReplEnd]],
+		}
+	}
+	return edits
+}
+
+// keys returns the lines normalized as selected by -w and -i.
+func (c *comparer) keys(lines []string) []string {
+	keys := make([]string, len(lines))
+	for i, l := range lines {
+		// The newline stays in the key, so that a last line without one
+		// differs from the same line with one. As in GNU diff, -w ignores
+		// it along with other white space.
+		if c.opts.ignoreSpace {
+			l = strings.Join(strings.Fields(l), "")
+		}
+		if c.opts.ignoreCase {
+			l = strings.ToLower(l)
+		}
+		keys[i] = l
+	}
+	return keys
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOffsets returns the offset of the start of each line, followed by the
+// total length.
+func lineOffsets(lines []string) []int {
+	offsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+	return offsets
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pgavlin/diff"
+)
+
+// normal renders edits, which must replace whole lines, in the default
+// output format of diff: each change is introduced by a command such as
+// "2,3c2" and lists the old lines prefixed by "< " and the new lines
+// prefixed by "> ".
+func normal(a string, edits []diff.Edit[string]) string {
+	var b strings.Builder
+	index := diff.NewLineIndex(a)
+	delta := 0
+	for _, e := range edits {
+		start, end := index.Lines(e.Span())
+		var newLines []string
+		if e.New != "" {
+			newLines = splitLines(e.New)
+		}
+		newStart := start + delta
+		delta += len(newLines) - (end - start)
+
+		switch {
+		case start == end:
+			fmt.Fprintf(&b, "%da%s\n", start, lineRange(newStart, newStart+len(newLines)))
+		case len(newLines) == 0:
+			fmt.Fprintf(&b, "%sd%d\n", lineRange(start, end), newStart)
+		default:
+			fmt.Fprintf(&b, "%sc%s\n", lineRange(start, end), lineRange(newStart, newStart+len(newLines)))
+		}
+		for i := start; i < end; i++ {
+			writeLine(&b, "< ", index.Line(i))
+		}
+		if start != end && len(newLines) != 0 {
+			b.WriteString("---\n")
+		}
+		for _, l := range newLines {
+			writeLine(&b, "> ", l)
+		}
+	}
+	return b.String()
+}
+
+// lineRange formats the zero-based, half-open range of lines [start, end)
+// as one-based line numbers.
+func lineRange(start, end int) string {
+	if end-start == 1 {
+		return fmt.Sprint(end)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end)
+}
+
+// writeLine writes a line with the given prefix, noting if it lacks a
+// newline.
+func writeLine(b *strings.Builder, prefix, line string) {
+	b.WriteString(prefix)
+	b.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+const tabSize = 8
+
+// sideBySide renders the differences between a and b in two columns that fit
+// in width columns, as "diff -y" does. Changed lines are marked with '|',
+// deleted lines with '<' and inserted lines with '>'. Like GNU diff, it pads
+// with tabs where possible.
+func sideBySide(a, b string, edits []diff.Edit[string], width int) string {
+	s := &sdiff{}
+	off := (width + tabSize + 3) / (2 * tabSize) * tabSize
+	s.halfWidth = off - 3
+	if width-off < s.halfWidth {
+		s.halfWidth = width - off
+	}
+	if s.halfWidth < 0 {
+		s.halfWidth = 0
+	}
+	s.column2 = width
+	if s.halfWidth > 0 {
+		s.column2 = off
+	}
+
+	aLines, bLines := splitLines(a), splitLines(b)
+	index := diff.NewLineIndex(a)
+	i, j := 0, 0
+	for _, e := range edits {
+		start, end := index.Lines(e.Span())
+		for ; i < start; i, j = i+1, j+1 {
+			s.row(&aLines[i], ' ', &bLines[j])
+		}
+		n := 0
+		if e.New != "" {
+			n = len(splitLines(e.New))
+		}
+		for ; i < end && n > 0; i, j, n = i+1, j+1, n-1 {
+			s.row(&aLines[i], '|', &bLines[j])
+		}
+		for ; i < end; i++ {
+			s.row(&aLines[i], '<', nil)
+		}
+		for ; n > 0; j, n = j+1, n-1 {
+			s.row(nil, '>', &bLines[j])
+		}
+	}
+	for ; i < len(aLines); i, j = i+1, j+1 {
+		s.row(&aLines[i], ' ', &bLines[j])
+	}
+	return s.b.String()
+}
+
+// sdiff renders the rows of a side-by-side diff.
+type sdiff struct {
+	b         strings.Builder
+	halfWidth int // the width of each column
+	column2   int // the offset of the right-hand column
+}
+
+// row writes a row with the given left and right lines, either of which may
+// be nil, separated by sep.
+func (s *sdiff) row(left *string, sep byte, right *string) {
+	newline, col := false, 0
+	if left != nil {
+		newline = strings.HasSuffix(*left, "\n")
+		col = s.half(*left, 0)
+	}
+	if sep != ' ' {
+		col = s.tab(col, (s.halfWidth+s.column2-1)/2) + 1
+		if sep == '|' && newline != strings.HasSuffix(*right, "\n") {
+			// Mark a change that adds or removes the final newline.
+			if newline {
+				sep = '/'
+			} else {
+				sep = '\\'
+			}
+		}
+		s.b.WriteByte(sep)
+	}
+	if right != nil {
+		newline = newline || strings.HasSuffix(*right, "\n")
+		if *right != "\n" {
+			col = s.tab(col, s.column2)
+			s.half(*right, col)
+		}
+	}
+	if newline {
+		s.b.WriteByte('\n')
+	}
+}
+
+// half writes as much of line as fits in a column that starts at indent,
+// and returns the width written.
+func (s *sdiff) half(line string, indent int) int {
+	in, out := 0, 0
+	for _, r := range line {
+		switch {
+		case r == '\n':
+			return out
+		case r == '\t':
+			spaces := tabSize - (in+indent)%tabSize
+			if in == out && out+spaces < s.halfWidth {
+				out += spaces
+				s.b.WriteByte('\t')
+			}
+			in += spaces
+		case r < ' ' || r == 0x7f:
+			// Control characters take no space.
+			if in < s.halfWidth {
+				s.b.WriteRune(r)
+			}
+		default:
+			if in++; in <= s.halfWidth {
+				out = in
+				if r == utf8.RuneError {
+					r = '?'
+				}
+				s.b.WriteRune(r)
+			}
+		}
+	}
+	return out
+}
+
+// tab pads from column from to column to, using tabs where possible, and
+// returns to.
+func (s *sdiff) tab(from, to int) int {
+	for tab := from + tabSize - from%tabSize; tab <= to; tab += tabSize {
+		s.b.WriteByte('\t')
+		from = tab
+	}
+	for ; from < to; from++ {
+		s.b.WriteByte(' ')
+	}
+	return to
+}
+
+// ANSI escape sequences used by --color, which match GNU diff's defaults.
+const (
+	colorHeader = "\x1b[1m"
+	colorLine   = "\x1b[36m"
+	colorDelete = "\x1b[31m"
+	colorInsert = "\x1b[32m"
+	colorReset  = "\x1b[0m"
+)
+
+// colorize adds color to the output of one comparison in the given format.
+func colorize(out string, f format) string {
+	if f == formatSideBySide {
+		return out
+	}
+	var b strings.Builder
+	side := colorDelete // the side of a context hunk being written
+	for i, line := range splitLines(out) {
+		text := strings.TrimSuffix(line, "\n")
+		var color string
+		switch f {
+		case formatUnified:
+			switch {
+			case i < 2:
+				color = colorHeader
+			case strings.HasPrefix(text, "@@"):
+				color = colorLine
+			case strings.HasPrefix(text, "-"):
+				color = colorDelete
+			case strings.HasPrefix(text, "+"):
+				color = colorInsert
+			}
+		case formatContext:
+			switch {
+			case i < 2:
+				color = colorHeader
+			case text == "***************", strings.HasPrefix(text, "\\"):
+			case strings.HasPrefix(text, "*** "):
+				color, side = colorLine, colorDelete
+			case strings.HasPrefix(text, "--- "):
+				color, side = colorLine, colorInsert
+			default:
+				color = side
+			}
+		default:
+			switch {
+			case strings.HasPrefix(text, "<"):
+				color = colorDelete
+			case strings.HasPrefix(text, ">"):
+				color = colorInsert
+			case text != "" && text[0] >= '0' && text[0] <= '9':
+				color = colorLine
+			}
+		}
+		if color == "" {
+			b.WriteString(line)
+		} else {
+			b.WriteString(color + text + colorReset + line[len(text):])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,304 @@
+// Diff compares files line by line. It accepts the commonly used options of
+// GNU diff and produces compatible output:
+//
+//	diff [OPTION]... FILE1 FILE2
+//
+//	-u, -U N, --unified[=N]  output N (default 3) lines of unified context
+//	-c, -C N, --context[=N]  output N (default 3) lines of copied context
+//	-y, --side-by-side       output in two columns
+//	-W N, --width=N          output at most N (default 130) columns with -y
+//	-r, --recursive          recursively compare any subdirectories found
+//	-N, --new-file           treat absent files as empty
+//	-q, --brief              report only when files differ
+//	-w, --ignore-all-space   ignore all white space
+//	-i, --ignore-case        ignore case differences in file contents
+//	--label LABEL            use LABEL instead of a file name and timestamp
+//	--color[=WHEN]           color output; WHEN is never, always or auto
+//	--help                   display this help and exit
+//
+// A FILE of "-" reads standard input. The exit status is 0 if the inputs are
+// the same, 1 if they differ, and 2 if there was trouble.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// usage is the text printed by --help.
+const usage = `Usage: diff [OPTION]... FILE1 FILE2
+Compare files line by line.
+
+  -u, -U N, --unified[=N]  output N (default 3) lines of unified context
+  -c, -C N, --context[=N]  output N (default 3) lines of copied context
+  -y, --side-by-side       output in two columns
+  -W N, --width=N          output at most N (default 130) columns with -y
+  -r, --recursive          recursively compare any subdirectories found
+  -N, --new-file           treat absent files as empty
+  -q, --brief              report only when files differ
+  -w, --ignore-all-space   ignore all white space
+  -i, --ignore-case        ignore case differences in file contents
+      --label LABEL        use LABEL instead of a file name and timestamp
+      --color[=WHEN]       color output; WHEN is never, always or auto
+      --help               display this help and exit
+
+A FILE of '-' reads standard input. The exit status is 0 if the inputs are
+the same, 1 if they differ, and 2 if there was trouble.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Exit statuses.
+const (
+	exitSame    = 0
+	exitDiffer  = 1
+	exitTrouble = 2
+)
+
+type format int
+
+const (
+	formatNormal format = iota
+	formatUnified
+	formatContext
+	formatSideBySide
+)
+
+// options holds the parsed command line.
+type options struct {
+	format      format
+	context     int
+	width       int
+	recursive   bool
+	newFile     bool
+	brief       bool
+	ignoreSpace bool
+	ignoreCase  bool
+	labels      []string
+	color       bool
+	help        bool
+
+	flags []string // the options as given, for the "diff ..." lines of directory comparisons
+	files []string
+}
+
+// parseArgs parses the command line. Short options may be combined, as in
+// "-ruN", and options that take an argument accept it either attached or as
+// the next argument.
+func parseArgs(args []string, isTerminal func() bool) (*options, error) {
+	opts := &options{context: -1, width: 130}
+	color := "never"
+
+	// As in GNU diff, the largest context length given wins, and -c and -u
+	// imply at least 3 lines.
+	styled := false
+	setFormat := func(f format) error {
+		if styled && opts.format != f {
+			return fmt.Errorf("conflicting output style options")
+		}
+		opts.format, styled = f, true
+		return nil
+	}
+	setContext := func(f format, arg string) error {
+		if err := setFormat(f); err != nil {
+			return err
+		}
+		n := 3
+		if arg != "" {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil || n < 0 {
+				return fmt.Errorf("invalid context length '%s'", arg)
+			}
+		}
+		if n > opts.context {
+			opts.context = n
+		}
+		return nil
+	}
+	setWidth := func(arg string) error {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid width '%s'", arg)
+		}
+		opts.width = n
+		return nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg, first := args[i], i
+		switch {
+		case arg == "--":
+			opts.files = append(opts.files, args[i+1:]...)
+			i = len(args)
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			// needValue returns the option's argument, which may be the
+			// next command line argument.
+			needValue := func() (string, error) {
+				if hasValue {
+					return value, nil
+				}
+				if i+1 == len(args) {
+					return "", fmt.Errorf("option '--%s' requires an argument", name)
+				}
+				i++
+				return args[i], nil
+			}
+			var err error
+			switch name {
+			case "unified":
+				err = setContext(formatUnified, value)
+			case "context":
+				err = setContext(formatContext, value)
+			case "side-by-side":
+				err = setFormat(formatSideBySide)
+			case "normal":
+				err = setFormat(formatNormal)
+			case "width":
+				var v string
+				if v, err = needValue(); err == nil {
+					err = setWidth(v)
+				}
+			case "recursive":
+				opts.recursive = true
+			case "new-file":
+				opts.newFile = true
+			case "brief":
+				opts.brief = true
+			case "ignore-all-space":
+				opts.ignoreSpace = true
+			case "ignore-case":
+				opts.ignoreCase = true
+			case "label":
+				var v string
+				if v, err = needValue(); err == nil {
+					opts.labels = append(opts.labels, v)
+				}
+			case "help":
+				// As in GNU diff, --help ignores the rest of the command
+				// line.
+				return &options{help: true}, nil
+			case "color":
+				color = "auto"
+				if hasValue {
+					color = value
+				}
+				if color != "never" && color != "always" && color != "auto" {
+					err = fmt.Errorf("invalid argument '%s' for '--color'", color)
+				}
+			default:
+				err = fmt.Errorf("unrecognized option '%s'", arg)
+			}
+			if err != nil {
+				return nil, err
+			}
+			opts.flags = append(opts.flags, args[first:i+1]...)
+
+		case len(arg) > 1 && arg[0] == '-':
+			for j := 1; j < len(arg); j++ {
+				c := arg[j]
+				// value returns the option's argument: the rest of this
+				// command line argument, or the next one.
+				value := func() (string, error) {
+					if rest := arg[j+1:]; rest != "" {
+						j = len(arg)
+						return rest, nil
+					}
+					if i+1 == len(args) {
+						return "", fmt.Errorf("option requires an argument -- '%c'", c)
+					}
+					i++
+					return args[i], nil
+				}
+				var err error
+				switch c {
+				case 'u':
+					err = setContext(formatUnified, "")
+				case 'c':
+					err = setContext(formatContext, "")
+				case 'U', 'C':
+					f := formatUnified
+					if c == 'C' {
+						f = formatContext
+					}
+					var v string
+					if v, err = value(); err == nil {
+						err = setContext(f, v)
+					}
+				case 'y':
+					err = setFormat(formatSideBySide)
+				case 'W':
+					var v string
+					if v, err = value(); err == nil {
+						err = setWidth(v)
+					}
+				case 'r':
+					opts.recursive = true
+				case 'N':
+					opts.newFile = true
+				case 'q':
+					opts.brief = true
+				case 'w':
+					opts.ignoreSpace = true
+				case 'i':
+					opts.ignoreCase = true
+				default:
+					err = fmt.Errorf("invalid option -- '%c'", c)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			opts.flags = append(opts.flags, args[first:i+1]...)
+
+		default:
+			opts.files = append(opts.files, arg)
+		}
+	}
+
+	switch len(opts.files) {
+	case 0:
+		return nil, fmt.Errorf("missing operand")
+	case 1:
+		return nil, fmt.Errorf("missing operand after '%s'", opts.files[0])
+	case 2:
+	default:
+		return nil, fmt.Errorf("extra operand '%s'", opts.files[2])
+	}
+	if opts.context < 0 {
+		opts.context = 3
+	}
+	opts.color = color == "always" || color == "auto" && isTerminal()
+	return opts, nil
+}
+
+// run runs the command with the given arguments and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	isTerminal := func() bool {
+		f, ok := stdout.(*os.File)
+		if !ok {
+			return false
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	opts, err := parseArgs(args, isTerminal)
+	if err != nil {
+		fmt.Fprintf(stderr, "diff: %v\ndiff: Try 'diff --help' for more information.\n", err)
+		return exitTrouble
+	}
+
+	if opts.help {
+		io.WriteString(stdout, usage)
+		return exitSame
+	}
+
+	c := &comparer{opts: opts, stdin: stdin, stdout: stdout, stderr: stderr, status: exitSame}
+	return c.compare(opts.files[0], opts.files[1])
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/testenv"
+)
+
+func TestParseArgs(t *testing.T) {
+	never := func() bool { return false }
+	for _, test := range []struct {
+		args []string
+		want options
+		err  string
+	}{
+		{[]string{"a", "b"}, options{context: 3, width: 130, files: []string{"a", "b"}}, ""},
+		{[]string{"-ruN", "a", "b"}, options{format: formatUnified, context: 3, width: 130, recursive: true, newFile: true, flags: []string{"-ruN"}, files: []string{"a", "b"}}, ""},
+		{[]string{"-U1", "a", "b"}, options{format: formatUnified, context: 1, width: 130, flags: []string{"-U1"}, files: []string{"a", "b"}}, ""},
+		{[]string{"-wiU", "5", "a", "b"}, options{format: formatUnified, context: 5, width: 130, ignoreSpace: true, ignoreCase: true, flags: []string{"-wiU", "5"}, files: []string{"a", "b"}}, ""},
+		{[]string{"-U0", "-u", "a", "b"}, options{format: formatUnified, context: 3, width: 130, flags: []string{"-U0", "-u"}, files: []string{"a", "b"}}, ""},
+		{[]string{"--context=2", "--label", "x", "--label=y", "a", "b"}, options{format: formatContext, context: 2, width: 130, labels: []string{"x", "y"}, flags: []string{"--context=2", "--label", "x", "--label=y"}, files: []string{"a", "b"}}, ""},
+		{[]string{"-yW", "40", "--color=always", "--", "-a", "b"}, options{format: formatSideBySide, context: 3, width: 40, color: true, flags: []string{"-yW", "40", "--color=always"}, files: []string{"-a", "b"}}, ""},
+		{[]string{"-u", "-y", "a", "b"}, options{}, "conflicting output style options"},
+		{[]string{"-Z", "a", "b"}, options{}, "invalid option -- 'Z'"},
+		{[]string{"--bogus", "a", "b"}, options{}, "unrecognized option '--bogus'"},
+		{[]string{"-U"}, options{}, "option requires an argument -- 'U'"},
+		{[]string{"--color=sometimes", "a", "b"}, options{}, "invalid argument 'sometimes' for '--color'"},
+		{[]string{"-u", "--help", "a"}, options{help: true}, ""},
+		{[]string{"a"}, options{}, "missing operand after 'a'"},
+		{[]string{"a", "b", "c"}, options{}, "extra operand 'c'"},
+	} {
+		got, err := parseArgs(test.args, never)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("parseArgs(%q): error %v, want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("parseArgs(%q) = %+v, want %+v", test.args, *got, test.want)
+		}
+	}
+}
+
+// writeFiles creates the given files, which may be in subdirectories, under
+// dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func runDiff(t *testing.T, dir string, args ...string) (string, string, int) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader("a\nb\n"), &stdout, &stderr)
+	return stdout.String(), stderr.String(), status
+}
+
+var testFiles = map[string]string{
+	"x":    "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+	"y":    "a\nB\nc\nd\ne\nf\ng\nh\nj\nk",
+	"w1":   "Hello World\n\tindented\tx\nfoo\n",
+	"w2":   "hello   world\n    indented x\nfoo\nbar\n",
+	"d1/f": "a\n",
+	"d2/f": "b\n",
+	"d1/s": "same\n",
+	"d2/s": "same\n",
+	"nl":   "a\nb\n",
+	"nonl": "a\nb",
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, testFiles)
+
+	for _, test := range []struct {
+		args   []string
+		stdout string
+		status int
+	}{
+		{[]string{"x", "x"}, "", 0},
+		{[]string{"x", "y"}, "2c2\n< b\n---\n> B\n9d8\n< i\n10a10\n> k\n\\ No newline at end of file\n", 1},
+		{[]string{"-q", "x", "y"}, "Files x and y differ\n", 1},
+		{[]string{"-U0", "--label", "x", "--label", "y", "x", "y"}, "--- x\n+++ y\n@@ -2 +2 @@\n-b\n+B\n@@ -9 +8,0 @@\n-i\n@@ -10,0 +10 @@\n+k\n\\ No newline at end of file\n", 1},
+		{[]string{"-i", "w1", "w2"}, "1,2c1,2\n< Hello World\n< \tindented\tx\n---\n> hello   world\n>     indented x\n3a4\n> bar\n", 1},
+		{[]string{"-wi", "w1", "w2"}, "3a4\n> bar\n", 1},
+		{[]string{"-w", "nl", "nonl"}, "", 0},
+		{[]string{"-i", "nonl", "nl"}, "2c2\n< b\n\\ No newline at end of file\n---\n> b\n", 1},
+		{[]string{"-y", "-W", "40", "w1", "w2"}, "Hello World\t   |\thello   world\n\tindented   |\t    indented x\nfoo\t\t\tfoo\n\t\t   >\tbar\n", 1},
+		{[]string{"--color=always", "-", "x"}, "\x1b[36m2a3,10\x1b[0m\n\x1b[32m> c\x1b[0m\n\x1b[32m> d\x1b[0m\n\x1b[32m> e\x1b[0m\n\x1b[32m> f\x1b[0m\n\x1b[32m> g\x1b[0m\n\x1b[32m> h\x1b[0m\n\x1b[32m> i\x1b[0m\n\x1b[32m> j\x1b[0m\n", 1},
+		{[]string{"d1", "d2"}, "diff d1/f d2/f\n1c1\n< a\n---\n> b\n", 1},
+		{[]string{"-N", "nonexistent", "d1/f"}, "0a1\n> a\n", 1},
+		{[]string{"x", "nonexistent"}, "", 2},
+		{[]string{"--help"}, usage, 0},
+	} {
+		stdout, stderr, status := runDiff(t, dir, test.args...)
+		if stdout != test.stdout || status != test.status {
+			t.Errorf("diff %s: got status %d, output:\n%q\nwant status %d, output:\n%q\nstderr: %s", strings.Join(test.args, " "), status, stdout, test.status, test.stdout, stderr)
+		}
+	}
+}
+
+// TestCompatible checks that the output matches that of the system's diff,
+// if it is GNU diff.
+func TestCompatible(t *testing.T) {
+	testenv.NeedsTool(t, "diff")
+	if out, err := exec.Command("diff", "--version").Output(); err != nil || !bytes.Contains(out, []byte("GNU")) {
+		t.Skip("diff is not GNU diff")
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, testFiles)
+	writeFiles(t, dir, map[string]string{
+		"d1/sub/only":   "x\n",
+		"d1/gone/deep":  "y\n",
+		"d2/new/n":      "n\n",
+		"d1/typ":        "t\n",
+		"d2/typ/child":  "c\n",
+		"d1/bin":        "\x00bin",
+		"d2/bin":        "\x00bon",
+		"d2/sub/other":  "z\n",
+		"d1/sub/shared": "1\n2\n3\n",
+		"d2/sub/shared": "1\n3\n4\n",
+		"d1/ws":         "x y\n",
+		"d2/ws":         "x  y\n",
+	})
+
+	for _, args := range [][]string{
+		{"x", "y"},
+		{"-u", "x", "y"},
+		{"-U1", "x", "y"},
+		{"-c", "x", "y"},
+		{"-C0", "y", "x"},
+		{"-y", "x", "y"},
+		{"-y", "-W", "50", "w1", "w2"},
+		{"-w", "-u", "w1", "w2"},
+		{"-i", "nl", "nonl"},
+		{"-iu", "nonl", "nl"},
+		{"-wi", "nl", "nonl"},
+		{"-ru", "-w", "d1", "d2"},
+		{"--color=always", "-u", "x", "y"},
+		{"--color=always", "-c", "x", "y"},
+		{"d1", "d2"},
+		{"-N", "d1", "d2"},
+		{"-r", "d1", "d2"},
+		{"-ruN", "d1", "d2"},
+		{"-rq", "d1", "d2"},
+		{"-r", "--label", "a", "-U", "1", "d1", "d2"},
+	} {
+		cmd := exec.Command("diff", args...)
+		cmd.Dir = dir
+		want, err := cmd.Output()
+		wantStatus := 0
+		if err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatal(err)
+			}
+			wantStatus = exitErr.ExitCode()
+		}
+
+		got, stderr, status := runDiff(t, dir, args...)
+		if got != string(want) || status != wantStatus {
+			t.Errorf("diff %s: got status %d, output:\n%s\nwant status %d, output:\n%s\nstderr: %s", strings.Join(args, " "), status, got, wantStatus, want, stderr)
+		}
+	}
+}
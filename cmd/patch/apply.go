@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pgavlin/diff"
+)
+
+// A result describes how a hunk was applied.
+type result struct {
+	applied bool
+	line    int // the one-based line of the new file at which the hunk starts
+	offset  int // the distance in lines from where the hunk header says it applies
+	fuzz    int // the number of context lines ignored at each end of the hunk
+}
+
+// message returns the message that patch prints for the n'th hunk. It
+// returns "" for a hunk that applied exactly where expected.
+func (r result) message(n int) string {
+	if !r.applied {
+		return fmt.Sprintf("Hunk #%d FAILED at %d.\n", n, r.line)
+	}
+	if r.offset == 0 && r.fuzz == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hunk #%d succeeded at %d", n, r.line)
+	if r.fuzz != 0 {
+		fmt.Fprintf(&b, " with fuzz %d", r.fuzz)
+	}
+	if r.offset != 0 {
+		fmt.Fprintf(&b, " (offset %d line%s)", r.offset, plural(r.offset))
+	}
+	b.WriteString(".\n")
+	return b.String()
+}
+
+func plural(n int) string {
+	if n == 1 || n == -1 {
+		return ""
+	}
+	return "s"
+}
+
+// applyHunks locates each hunk in content and returns the edits that apply
+// the hunks that were found, along with a result for each hunk.
+//
+// Each hunk is first sought where its header says it applies, adjusted by
+// the offset at which the previous hunk was found, and then at increasing
+// distances before and after that line. Hunks may not overlap. If a hunk
+// cannot be found with all of its context, it is sought again ignoring up
+// to maxFuzz lines of context at its start and end.
+func applyHunks(content string, hunks []*hunk, maxFuzz int) ([]diff.Edit[string], []result) {
+	lines := splitLines(content)
+	offsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+
+	var edits []diff.Edit[string]
+	results := make([]result, len(hunks))
+	lastOffset, delta, minPos := 0, 0, 0
+	for n, h := range hunks {
+		old, new := h.side('+'), h.side('-')
+		expected := h.oldStart - 1
+		if len(old) == 0 {
+			expected = h.oldStart // insert after the given line
+		}
+
+		results[n] = result{line: h.oldStart + delta}
+		for fuzz := 0; fuzz <= maxFuzz; fuzz++ {
+			lead, trail := trimmable(h, fuzz)
+			if fuzz > 0 && lead+trail == 0 {
+				break // no more context to ignore
+			}
+			o, nw := old[lead:len(old)-trail], new[lead:len(new)-trail]
+			pos, ok := search(lines, o, expected+lastOffset+lead, minPos)
+			if !ok {
+				continue
+			}
+
+			start := pos - lead // where the whole hunk would start
+			lastOffset = start - expected
+			results[n] = result{applied: true, line: start + 1 + delta, offset: lastOffset, fuzz: fuzz}
+			edits = append(edits, diff.Edit[string]{Start: offsets[pos], End: offsets[pos+len(o)], New: strings.Join(nw, "")})
+			delta += len(nw) - len(o)
+			minPos = pos + len(o)
+			break
+		}
+	}
+	return edits, results
+}
+
+// trimmable returns the numbers of context lines that may be ignored at the
+// start and end of h with the given fuzz factor.
+func trimmable(h *hunk, fuzz int) (lead, trail int) {
+	for lead < fuzz && lead < len(h.lines) && h.lines[lead].kind == ' ' {
+		lead++
+	}
+	for trail < fuzz && trail < len(h.lines)-lead && h.lines[len(h.lines)-1-trail].kind == ' ' {
+		trail++
+	}
+	return lead, trail
+}
+
+// search returns the position nearest to at, and no earlier than minPos, at
+// which want occurs in lines.
+func search(lines, want []string, at, minPos int) (int, bool) {
+	maxPos := len(lines) - len(want)
+	if maxPos < minPos {
+		return 0, false
+	}
+	if at < minPos {
+		at = minPos
+	} else if at > maxPos {
+		at = maxPos
+	}
+	for d := 0; at-d >= minPos || at+d <= maxPos; d++ {
+		if pos := at + d; pos <= maxPos && matches(lines[pos:], want) {
+			return pos, true
+		}
+		if pos := at - d; d > 0 && pos >= minPos && matches(lines[pos:], want) {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+func matches(lines, want []string) bool {
+	for i, w := range want {
+		if lines[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// formatHunks renders hunks as a unified diff with the given header labels.
+func formatHunks(oldLabel, newLabel string, hunks []*hunk) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldLabel, newLabel)
+	for _, h := range hunks {
+		oldCount, newCount := len(h.side('+')), len(h.side('-'))
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", formatRange(h.oldStart, oldCount), formatRange(h.newStart, newCount))
+		for _, l := range h.lines {
+			b.WriteByte(l.kind)
+			b.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func formatRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,483 @@
+// Patch applies a diff file to originals. It accepts the commonly used
+// options of GNU patch:
+//
+//	patch [OPTION]... [ORIGFILE [PATCHFILE]]
+//
+//	-p N, --strip=N          strip N leading components from file names
+//	-R, --reverse            apply the patch in reverse
+//	--dry-run                report what would happen without changing files
+//	-F N, --fuzz=N           ignore up to N (default 2) lines of context
+//	-i FILE, --input=FILE    read the patch from FILE instead of stdin
+//	-d DIR, --directory=DIR  change to DIR before applying the patch
+//	-b, --backup             save the original of each file as FILE.orig
+//	--no-backup-if-mismatch  do not save originals of files that mismatch
+//	-E, --remove-empty-files remove files that are empty after patching
+//	-s, --silent, --quiet    only report errors
+//
+// The patch may contain unified or context diffs of any number of files,
+// including git-style diffs that create, delete or rename files. Hunks that
+// cannot be applied are saved in unified form to FILE.rej. Without -p, file
+// names are stripped to their last component; git-style diffs usually need
+// -p1.
+//
+// The exit status is 0 if all hunks applied, 1 if some were rejected, and 2
+// if there was trouble.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/diff"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Exit statuses.
+const (
+	exitOK       = 0
+	exitRejected = 1
+	exitTrouble  = 2
+)
+
+// options holds the parsed command line.
+type options struct {
+	strip            int // -1 if not given
+	reverse          bool
+	dryRun           bool
+	fuzz             int
+	input            string
+	dir              string
+	backup           bool
+	backupIfMismatch bool
+	removeEmpty      bool
+	silent           bool
+	origFile         string
+}
+
+// parseArgs parses the command line. Short options may be combined, as in
+// "-Rp1", and options that take an argument accept it either attached or as
+// the next argument.
+func parseArgs(args []string) (*options, error) {
+	opts := &options{strip: -1, fuzz: 2, backupIfMismatch: true}
+	number := func(name, arg string) (int, error) {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s argument must be a non-negative number: '%s'", name, arg)
+		}
+		return n, nil
+	}
+
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			// needValue returns the option's argument, which may be the
+			// next command line argument.
+			needValue := func() (string, error) {
+				if hasValue {
+					return value, nil
+				}
+				if i+1 == len(args) {
+					return "", fmt.Errorf("option '--%s' requires an argument", name)
+				}
+				i++
+				return args[i], nil
+			}
+			var err error
+			var v string
+			switch name {
+			case "strip":
+				if v, err = needValue(); err == nil {
+					opts.strip, err = number("strip", v)
+				}
+			case "fuzz":
+				if v, err = needValue(); err == nil {
+					opts.fuzz, err = number("fuzz", v)
+				}
+			case "input":
+				opts.input, err = needValue()
+			case "directory":
+				opts.dir, err = needValue()
+			case "reverse":
+				opts.reverse = true
+			case "dry-run":
+				opts.dryRun = true
+			case "backup":
+				opts.backup = true
+			case "backup-if-mismatch":
+				opts.backupIfMismatch = true
+			case "no-backup-if-mismatch":
+				opts.backupIfMismatch = false
+			case "remove-empty-files":
+				opts.removeEmpty = true
+			case "silent", "quiet":
+				opts.silent = true
+			default:
+				err = fmt.Errorf("unrecognized option '%s'", arg)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+		case len(arg) > 1 && arg[0] == '-':
+			for j := 1; j < len(arg); j++ {
+				c := arg[j]
+				// value returns the option's argument: the rest of this
+				// command line argument, or the next one.
+				value := func() (string, error) {
+					if rest := arg[j+1:]; rest != "" {
+						j = len(arg)
+						return rest, nil
+					}
+					if i+1 == len(args) {
+						return "", fmt.Errorf("option requires an argument -- '%c'", c)
+					}
+					i++
+					return args[i], nil
+				}
+				var err error
+				var v string
+				switch c {
+				case 'p':
+					if v, err = value(); err == nil {
+						opts.strip, err = number("strip", v)
+					}
+				case 'F':
+					if v, err = value(); err == nil {
+						opts.fuzz, err = number("fuzz", v)
+					}
+				case 'i':
+					opts.input, err = value()
+				case 'd':
+					opts.dir, err = value()
+				case 'R':
+					opts.reverse = true
+				case 'b':
+					opts.backup = true
+				case 'E':
+					opts.removeEmpty = true
+				case 's':
+					opts.silent = true
+				default:
+					err = fmt.Errorf("invalid option -- '%c'", c)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	switch len(operands) {
+	case 2:
+		if opts.input != "" {
+			return nil, fmt.Errorf("patch file given twice")
+		}
+		opts.input = operands[1]
+		fallthrough
+	case 1:
+		opts.origFile = operands[0]
+	case 0:
+	default:
+		return nil, fmt.Errorf("extra operand '%s'", operands[2])
+	}
+	return opts, nil
+}
+
+// run runs the command with the given arguments and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "patch: %v\npatch: Try 'patch --help' for more information.\n", err)
+		return exitTrouble
+	}
+
+	var input []byte
+	if opts.input == "" || opts.input == "-" {
+		input, err = io.ReadAll(stdin)
+	} else {
+		input, err = os.ReadFile(opts.input)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "patch: **** %v\n", err)
+		return exitTrouble
+	}
+
+	patches, err := parsePatch(string(input))
+	if err != nil {
+		fmt.Fprintf(stderr, "patch: **** %v\n", err)
+		return exitTrouble
+	}
+	if len(patches) == 0 {
+		fmt.Fprintf(stderr, "patch: **** Only garbage was found in the patch input.\n")
+		return exitTrouble
+	}
+
+	p := &patcher{opts: opts, stdout: stdout, stderr: stderr, status: exitOK}
+	for _, fp := range patches {
+		if opts.reverse {
+			fp.reverse()
+		}
+		p.patch(fp)
+	}
+	return p.status
+}
+
+// A patcher applies patches to files and accumulates the exit status.
+type patcher struct {
+	opts           *options
+	stdout, stderr io.Writer
+	status         int
+}
+
+func (p *patcher) setStatus(status int) {
+	if status > p.status {
+		p.status = status
+	}
+}
+
+// printf writes a message unless -s was given.
+func (p *patcher) printf(format string, args ...any) {
+	if !p.opts.silent {
+		fmt.Fprintf(p.stdout, format, args...)
+	}
+}
+
+// strip removes leading components from name, as selected by -p.
+func (p *patcher) strip(name string) string {
+	if name == "/dev/null" || name == "" {
+		return name
+	}
+	if p.opts.strip < 0 {
+		return path.Base(name)
+	}
+	for n := p.opts.strip; n > 0; n-- {
+		i := strings.IndexByte(name, '/')
+		if i < 0 {
+			return ""
+		}
+		name = name[i+1:]
+	}
+	return name
+}
+
+// safe reports whether name stays within the directory being patched,
+// that is, whether it is relative and has no ".." component.
+func safe(name string) bool {
+	if name == "/dev/null" {
+		return true
+	}
+	if path.IsAbs(name) || filepath.IsAbs(name) {
+		return false
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// path returns the path of the named file, relative to -d if given.
+func (p *patcher) path(name string) string {
+	return filepath.Join(p.opts.dir, filepath.FromSlash(name))
+}
+
+func (p *patcher) exists(name string) bool {
+	if name == "" || name == "/dev/null" {
+		return false
+	}
+	_, err := os.Stat(p.path(name))
+	return err == nil
+}
+
+// patch applies one file's patch.
+func (p *patcher) patch(fp *filePatch) {
+	oldName, newName := p.strip(fp.oldName), p.strip(fp.newName)
+
+	// Like GNU patch, ignore names that would escape the directory being
+	// patched, as if the patch did not give them.
+	var dangerous []string
+	if !safe(oldName) {
+		dangerous, oldName = append(dangerous, oldName), ""
+	}
+	if !safe(newName) {
+		if len(dangerous) == 0 || dangerous[0] != newName {
+			dangerous = append(dangerous, newName)
+		}
+		newName = ""
+	}
+
+	// Choose the file to read and the file to write.
+	var source, target string
+	switch {
+	case p.opts.origFile != "":
+		source, target = p.opts.origFile, p.opts.origFile
+	case fp.create:
+		target = newName
+		if p.exists(target) {
+			if info, err := os.Stat(p.path(target)); err == nil && info.Size() != 0 {
+				p.printf("The next patch would create the file %s,\nwhich already exists!  Skipping patch.\n", target)
+				p.reject(fp, target, fp.hunks, "ignored")
+				return
+			}
+			source = target
+		}
+	case fp.delete:
+		source, target = oldName, oldName
+	case fp.rename || fp.copy:
+		if oldName != "" {
+			source, target = oldName, newName
+		}
+	default:
+		for _, name := range []string{oldName, newName} {
+			if p.exists(name) {
+				source, target = name, name
+				break
+			}
+		}
+		if target == "" && emptySide(fp.hunks, '+') {
+			// The patch adds every line of an empty file, which may be
+			// created.
+			target = newName
+		}
+	}
+	if target == "" || source != "" && !p.exists(source) {
+		for _, name := range dangerous {
+			p.printf("Ignoring potentially dangerous file name %s\n", name)
+		}
+		p.printf("can't find file to patch at input line %d\nPerhaps you should have used the -p or --strip option?\nNo file to patch.  Skipping patch.\n", fp.line)
+		p.setStatus(exitRejected)
+		p.printf("%d out of %d hunk%s ignored\n", len(fp.hunks), len(fp.hunks), plural(len(fp.hunks)))
+		return
+	}
+
+	verb := "patching"
+	if p.opts.dryRun {
+		verb = "checking"
+	}
+	if fp.rename {
+		p.printf("%s file %s (renamed from %s)\n", verb, target, source)
+	} else if fp.copy {
+		p.printf("%s file %s (copied from %s)\n", verb, target, source)
+	} else {
+		p.printf("%s file %s\n", verb, target)
+	}
+
+	var content []byte
+	mode := fs.FileMode(0o666)
+	if source != "" {
+		info, err := os.Stat(p.path(source))
+		if err != nil {
+			p.trouble(err)
+			return
+		}
+		if content, err = os.ReadFile(p.path(source)); err != nil {
+			p.trouble(err)
+			return
+		}
+		mode = info.Mode().Perm()
+	}
+
+	edits, results := applyHunks(string(content), fp.hunks, p.opts.fuzz)
+	var rejected []*hunk
+	mismatch := false
+	for i, r := range results {
+		if msg := r.message(i + 1); msg != "" {
+			if r.applied {
+				p.printf("%s", msg)
+			} else {
+				fmt.Fprint(p.stdout, msg)
+			}
+			mismatch = true
+		}
+		if !r.applied {
+			rejected = append(rejected, fp.hunks[i])
+		}
+	}
+	result, err := diff.Apply(string(content), edits)
+	if err != nil {
+		p.trouble(err) // can't happen: hunks do not overlap
+		return
+	}
+
+	if !p.opts.dryRun {
+		if source != "" && (p.opts.backup || mismatch && p.opts.backupIfMismatch) {
+			if err := os.WriteFile(p.path(source)+".orig", content, mode); err != nil {
+				p.trouble(err)
+				return
+			}
+		}
+		if err := p.write(fp, source, target, result, mode); err != nil {
+			p.trouble(err)
+			return
+		}
+	}
+	if len(rejected) != 0 {
+		p.reject(fp, target, rejected, "FAILED")
+	}
+}
+
+// write writes the patched content of a file.
+func (p *patcher) write(fp *filePatch, source, target, result string, mode fs.FileMode) error {
+	if result == "" && (fp.delete || p.opts.removeEmpty) {
+		return os.Remove(p.path(target))
+	}
+	if dir := filepath.Dir(p.path(target)); dir != "" {
+		if err := os.MkdirAll(dir, 0o777); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(p.path(target), []byte(result), mode); err != nil {
+		return err
+	}
+	if fp.rename && source != target {
+		return os.Remove(p.path(source))
+	}
+	return nil
+}
+
+// reject reports that hunks could not be applied to target, and saves them
+// to target.rej.
+func (p *patcher) reject(fp *filePatch, target string, hunks []*hunk, how string) {
+	p.setStatus(exitRejected)
+	s := plural(len(fp.hunks))
+	if p.opts.dryRun {
+		fmt.Fprintf(p.stdout, "%d out of %d hunk%s %s\n", len(hunks), len(fp.hunks), s, how)
+		return
+	}
+	rej := target + ".rej"
+	fmt.Fprintf(p.stdout, "%d out of %d hunk%s %s -- saving rejects to file %s\n", len(hunks), len(fp.hunks), s, how, rej)
+	if err := os.WriteFile(p.path(rej), []byte(formatHunks(target+fp.oldStamp, target+fp.newStamp, hunks)), 0o666); err != nil {
+		p.trouble(err)
+	}
+}
+
+// trouble reports err and records that patching failed.
+func (p *patcher) trouble(err error) {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = fmt.Errorf("%s: %v", pathErr.Path, pathErr.Err)
+	}
+	fmt.Fprintf(p.stderr, "patch: **** %v\n", err)
+	p.setStatus(exitTrouble)
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/testenv"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		want options
+		err  string
+	}{
+		{nil, options{strip: -1, fuzz: 2, backupIfMismatch: true}, ""},
+		{[]string{"-Rp1", "--dry-run"}, options{strip: 1, reverse: true, dryRun: true, fuzz: 2, backupIfMismatch: true}, ""},
+		{[]string{"-p", "0", "-F1", "-i", "x.diff", "-bsE"}, options{strip: 0, fuzz: 1, input: "x.diff", backup: true, backupIfMismatch: true, removeEmpty: true, silent: true}, ""},
+		{[]string{"--strip=2", "--fuzz", "0", "--directory=d", "--no-backup-if-mismatch", "f"}, options{strip: 2, dir: "d", origFile: "f"}, ""},
+		{[]string{"f", "x.diff"}, options{strip: -1, fuzz: 2, backupIfMismatch: true, input: "x.diff", origFile: "f"}, ""},
+		{[]string{"-p", "x"}, options{}, "strip argument must be a non-negative number: 'x'"},
+		{[]string{"-F"}, options{}, "option requires an argument -- 'F'"},
+		{[]string{"-Z"}, options{}, "invalid option -- 'Z'"},
+		{[]string{"--bogus"}, options{}, "unrecognized option '--bogus'"},
+		{[]string{"a", "b", "c"}, options{}, "extra operand 'c'"},
+	} {
+		got, err := parseArgs(test.args)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("parseArgs(%q): error %v, want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("parseArgs(%q) = %+v, want %+v", test.args, *got, test.want)
+		}
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	const input = `Some leading text.
+diff --git a/new b/new
+new file mode 100644
+index 0000000..45b983b
+--- /dev/null
++++ b/new
+@@ -0,0 +1 @@
++hi
+diff --git a/old b/renamed
+similarity index 100%
+rename from old
+rename to renamed
+Index: f
+--- f	2000-01-01 00:00:00
++++ f	2000-01-01 00:00:01
+@@ -1,2 +1,2 @@
+ a
+-b
++c
+\ No newline at end of file
+*** g
+--- g
+***************
+*** 1,2 ****
+! x
+  y
+--- 1,2 ----
+! z
+  y
+`
+	patches, err := parsePatch(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 4 {
+		t.Fatalf("got %d patches, want 4", len(patches))
+	}
+	for i, want := range []filePatch{
+		{oldName: "/dev/null", newName: "b/new", git: true, create: true},
+		{oldName: "a/old", newName: "b/renamed", git: true, rename: true},
+		{oldName: "f", newName: "f"},
+		{oldName: "g", newName: "g"},
+	} {
+		got := patches[i]
+		if got.oldName != want.oldName || got.newName != want.newName || got.git != want.git || got.create != want.create || got.delete != want.delete || got.rename != want.rename {
+			t.Errorf("patch %d: got %+v, want %+v", i, *got, want)
+		}
+	}
+	if got, want := formatHunks("f", "f", patches[2].hunks), "--- f\n+++ f\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n\\ No newline at end of file\n"; got != want {
+		t.Errorf("unified hunks: got %q, want %q", got, want)
+	}
+	if got, want := formatHunks("g", "g", patches[3].hunks), "--- g\n+++ g\n@@ -1,2 +1,2 @@\n-x\n+z\n y\n"; got != want {
+		t.Errorf("context hunks: got %q, want %q", got, want)
+	}
+
+	if _, err := parsePatch("--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n"); err == nil {
+		t.Error("parsePatch accepted a truncated hunk")
+	}
+}
+
+// writeFiles creates the given files, which may be in subdirectories, under
+// dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFiles returns the contents of the files under dir.
+func readFiles(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		files[filepath.ToSlash(rel)] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func runPatch(t *testing.T, dir, patch string, args ...string) (string, string, int) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(patch), &stdout, &stderr)
+	return stdout.String(), stderr.String(), status
+}
+
+func lines(start, end int, replace map[int]string) string {
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		if s, ok := replace[i]; ok {
+			b.WriteString(s)
+		} else {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+	}
+	return b.String()
+}
+
+const twoHunks = `--- a/f
++++ b/f
+@@ -2,7 +2,7 @@
+ line 2
+ line 3
+ line 4
+-line 5
++six
+ line 6
+ line 7
+ line 8
+@@ -12,7 +12,7 @@
+ line 12
+ line 13
+ line 14
+-line 15
++fifteen
+ line 16
+ line 17
+ line 18
+`
+
+var patchTests = []struct {
+	name   string
+	args   []string
+	patch  string
+	before map[string]string
+	after  map[string]string
+	stdout string
+	status int
+}{
+	{
+		name:   "exact",
+		args:   []string{"-p1"},
+		patch:  twoHunks,
+		before: map[string]string{"f": lines(1, 20, nil)},
+		after:  map[string]string{"f": lines(1, 20, map[int]string{5: "six\n", 15: "fifteen\n"})},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "offset",
+		patch:  twoHunks,
+		before: map[string]string{"f": "x\ny\n" + lines(1, 20, nil)},
+		after: map[string]string{
+			"f":      "x\ny\n" + lines(1, 20, map[int]string{5: "six\n", 15: "fifteen\n"}),
+			"f.orig": "x\ny\n" + lines(1, 20, nil),
+		},
+		stdout: "patching file f\nHunk #1 succeeded at 4 (offset 2 lines).\nHunk #2 succeeded at 14 (offset 2 lines).\n",
+	},
+	{
+		name:   "fuzz",
+		args:   []string{"--no-backup-if-mismatch"},
+		patch:  twoHunks,
+		before: map[string]string{"f": lines(1, 20, map[int]string{2: "X\n"})},
+		after:  map[string]string{"f": lines(1, 20, map[int]string{2: "X\n", 5: "six\n", 15: "fifteen\n"})},
+		stdout: "patching file f\nHunk #1 succeeded at 2 with fuzz 1.\n",
+	},
+	{
+		name:   "reject",
+		args:   []string{"-F0"},
+		patch:  twoHunks,
+		before: map[string]string{"f": lines(1, 20, map[int]string{2: "X\n"})},
+		after: map[string]string{
+			"f":      lines(1, 20, map[int]string{2: "X\n", 15: "fifteen\n"}),
+			"f.orig": lines(1, 20, map[int]string{2: "X\n"}),
+			"f.rej":  "--- f\n+++ f\n@@ -2,7 +2,7 @@\n line 2\n line 3\n line 4\n-line 5\n+six\n line 6\n line 7\n line 8\n",
+		},
+		stdout: "patching file f\nHunk #1 FAILED at 2.\n1 out of 2 hunks FAILED -- saving rejects to file f.rej\n",
+		status: 1,
+	},
+	{
+		name:   "dry run",
+		args:   []string{"--dry-run", "-F0"},
+		patch:  twoHunks,
+		before: map[string]string{"f": lines(1, 20, map[int]string{2: "X\n"})},
+		after:  map[string]string{"f": lines(1, 20, map[int]string{2: "X\n"})},
+		stdout: "checking file f\nHunk #1 FAILED at 2.\n1 out of 2 hunks FAILED\n",
+		status: 1,
+	},
+	{
+		name:   "reverse",
+		args:   []string{"-R", "-s"},
+		patch:  twoHunks,
+		before: map[string]string{"f": lines(1, 20, map[int]string{5: "six\n", 15: "fifteen\n"})},
+		after:  map[string]string{"f": lines(1, 20, nil)},
+	},
+	{
+		name:   "missing",
+		patch:  twoHunks,
+		before: map[string]string{},
+		after:  map[string]string{},
+		stdout: "can't find file to patch at input line 3\nPerhaps you should have used the -p or --strip option?\nNo file to patch.  Skipping patch.\n2 out of 2 hunks ignored\n",
+		status: 1,
+	},
+	{
+		name:   "dangerous",
+		args:   []string{"-p1", "-d", "work"},
+		patch:  "--- a/../escaped\n+++ b/../escaped\n@@ -0,0 +1 @@\n+x\n",
+		before: map[string]string{"work/f": "a\n"},
+		after:  map[string]string{"work/f": "a\n"},
+		stdout: "Ignoring potentially dangerous file name ../escaped\ncan't find file to patch at input line 3\nPerhaps you should have used the -p or --strip option?\nNo file to patch.  Skipping patch.\n1 out of 1 hunk ignored\n",
+		status: 1,
+	},
+	{
+		name:   "dangerous new name",
+		args:   []string{"-p1", "-d", "work"},
+		patch:  "--- a/f\n+++ b/../f\n@@ -1 +1 @@\n-a\n+b\n",
+		before: map[string]string{"work/f": "a\n"},
+		after:  map[string]string{"work/f": "b\n"},
+		stdout: "patching file f\n",
+	},
+	{
+		name: "git",
+		args: []string{"-p1"},
+		patch: `diff --git a/dir/fresh b/dir/fresh
+new file mode 100644
+--- /dev/null
++++ b/dir/fresh
+@@ -0,0 +1 @@
++hi
+diff --git a/gone b/gone
+deleted file mode 100644
+--- a/gone
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/old b/renamed
+similarity index 80%
+rename from old
+rename to renamed
+--- a/old
++++ b/renamed
+@@ -1,2 +1,2 @@
+ a
+-b
++c
+`,
+		before: map[string]string{"gone": "bye\n", "old": "a\nb\n"},
+		after:  map[string]string{"dir/fresh": "hi\n", "renamed": "a\nc\n"},
+		stdout: "patching file dir/fresh\npatching file gone\npatching file renamed (renamed from old)\n",
+	},
+	{
+		name:   "empty",
+		patch:  "--- f\t2000-01-01 00:00:00\n+++ f\t2000-01-01 00:00:01\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		before: map[string]string{"f": "a\nb\n"},
+		after:  map[string]string{"f": ""},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "empty git",
+		args:   []string{"-p1"},
+		patch:  "diff --git a/f b/f\nindex 3b18e51..0000000 100644\n--- a/f\n+++ b/f\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		before: map[string]string{"f": "a\nb\n"},
+		after:  map[string]string{"f": ""},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "remove empty",
+		args:   []string{"-E"},
+		patch:  "--- f\t2000-01-01 00:00:00\n+++ f\t2000-01-01 00:00:01\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		before: map[string]string{"f": "a\nb\n"},
+		after:  map[string]string{},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "epoch",
+		patch:  "--- f\t2000-01-01 00:00:00.000000000 +0000\n+++ f\t1970-01-01 00:00:00.000000000 +0000\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		before: map[string]string{"f": "a\nb\n"},
+		after:  map[string]string{},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "reverse creation",
+		args:   []string{"-R"},
+		patch:  "--- f\t2000-01-01 00:00:00\n+++ f\t2000-01-01 00:00:01\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		before: map[string]string{"f": "a\nb\n"},
+		after:  map[string]string{"f": ""},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "creation",
+		patch:  "--- f\t2000-01-01 00:00:00\n+++ f\t2000-01-01 00:00:01\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		before: map[string]string{},
+		after:  map[string]string{"f": "a\nb\n"},
+		stdout: "patching file f\n",
+	},
+	{
+		name:   "garbage",
+		patch:  "nothing to see here\n",
+		before: map[string]string{},
+		after:  map[string]string{},
+		status: 2,
+	},
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range patchTests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, test.before)
+
+			stdout, stderr, status := runPatch(t, dir, test.patch, test.args...)
+			if stdout != test.stdout || status != test.status {
+				t.Errorf("got status %d, output:\n%q\nwant status %d, output:\n%q\nstderr: %s", status, stdout, test.status, test.stdout, stderr)
+			}
+			if got := readFiles(t, dir); !reflect.DeepEqual(got, test.after) {
+				t.Errorf("got files %q, want %q", keys(got), keys(test.after))
+				for name, want := range test.after {
+					if got[name] != want {
+						t.Errorf("%s: got %q, want %q", name, got[name], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestCompatible checks that the output and the patched files match those
+// of the system's patch, if it is GNU patch.
+func TestCompatible(t *testing.T) {
+	testenv.NeedsTool(t, "patch")
+	if out, err := exec.Command("patch", "--version").Output(); err != nil || !bytes.Contains(out, []byte("GNU")) {
+		t.Skip("patch is not GNU patch")
+	}
+
+	for _, test := range patchTests {
+		if test.name == "missing" || test.name == "dangerous" {
+			continue // GNU patch explains a missing file differently
+		}
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, test.before)
+			cmd := exec.Command("patch", append([]string{"-f"}, test.args...)...)
+			cmd.Dir = dir
+			cmd.Stdin = strings.NewReader(test.patch)
+			want, err := cmd.Output()
+			wantStatus := 0
+			if err != nil {
+				exitErr, ok := err.(*exec.ExitError)
+				if !ok {
+					t.Fatal(err)
+				}
+				wantStatus = exitErr.ExitCode()
+			}
+			wantFiles := readFiles(t, dir)
+
+			dir = t.TempDir()
+			writeFiles(t, dir, test.before)
+			got, stderr, status := runPatch(t, dir, test.patch, test.args...)
+			if got != string(want) || status != wantStatus {
+				t.Errorf("got status %d, output:\n%s\nwant status %d, output:\n%s\nstderr: %s", status, got, wantStatus, want, stderr)
+			}
+			if gotFiles := readFiles(t, dir); !reflect.DeepEqual(gotFiles, wantFiles) {
+				t.Errorf("got files %q, want %q", gotFiles, wantFiles)
+			}
+		})
+	}
+}
+
+func TestSafe(t *testing.T) {
+	for name, want := range map[string]bool{
+		"f":           true,
+		"dir/f":       true,
+		"a..b":        true,
+		"/dev/null":   true,
+		"/etc/f":      false,
+		"..":          false,
+		"../f":        false,
+		"dir/../../f": false,
+	} {
+		if got := safe(name); got != want {
+			t.Errorf("safe(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func keys(m map[string]string) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
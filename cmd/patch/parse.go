@@ -0,0 +1,437 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A filePatch is the part of a patch that applies to one file.
+type filePatch struct {
+	oldName, newName   string // names from the ---/+++ (or ***/---) or "diff --git" lines
+	oldStamp, newStamp string // any timestamps following those names
+	git                bool   // the patch has a "diff --git" header
+	create, delete     bool   // the patch creates or deletes the file
+	rename, copy       bool   // the patch renames or copies the file
+	line               int    // the input line of the first hunk
+	hunks              []*hunk
+}
+
+// A hunk is a contiguous change to a file.
+type hunk struct {
+	oldStart, newStart int // one-based first lines, or the line before an empty range
+	lines              []hunkLine
+}
+
+// A hunkLine is a line of a hunk. Its kind is ' ' for context, '-' for a
+// deleted line or '+' for an inserted line. Its text includes the newline
+// unless the line is the last line of a file that lacks one.
+type hunkLine struct {
+	kind byte
+	text string
+}
+
+// side returns the lines of the hunk that are not of the kind omit, which
+// are the lines of the old file if omit is '+' and of the new file if omit
+// is '-'.
+func (h *hunk) side(omit byte) []string {
+	var lines []string
+	for _, l := range h.lines {
+		if l.kind != omit {
+			lines = append(lines, l.text)
+		}
+	}
+	return lines
+}
+
+// reverse swaps the old and new sides of the hunk.
+func (h *hunk) reverse() {
+	h.oldStart, h.newStart = h.newStart, h.oldStart
+	for i, l := range h.lines {
+		switch l.kind {
+		case '-':
+			h.lines[i].kind = '+'
+		case '+':
+			h.lines[i].kind = '-'
+		}
+	}
+}
+
+// reverse swaps the old and new sides of the patch.
+func (p *filePatch) reverse() {
+	p.oldName, p.newName = p.newName, p.oldName
+	p.oldStamp, p.newStamp = p.newStamp, p.oldStamp
+	p.create, p.delete = p.delete, p.create
+	for _, h := range p.hunks {
+		h.reverse()
+	}
+}
+
+// A parser splits a patch into lines and parses them.
+type parser struct {
+	lines []string
+	i     int
+}
+
+// parsePatch parses a patch that contains unified or context diffs of any
+// number of files, possibly with git extended headers. Text that is not part
+// of a diff is ignored.
+func parsePatch(input string) ([]*filePatch, error) {
+	p := &parser{lines: strings.SplitAfter(input, "\n")}
+	if p.lines[len(p.lines)-1] == "" {
+		p.lines = p.lines[:len(p.lines)-1]
+	}
+
+	var patches []*filePatch
+	var git *filePatch // the patch introduced by the last "diff --git" line, if it is still open
+	for p.i < len(p.lines) {
+		line := strings.TrimSuffix(p.lines[p.i], "\n")
+		next := ""
+		if p.i+1 < len(p.lines) {
+			next = p.lines[p.i+1]
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			git = &filePatch{git: true}
+			git.oldName, git.newName = parseGitNames(line[len("diff --git "):])
+			patches = append(patches, git)
+			p.i++
+
+		case git != nil && len(git.hunks) == 0 && isGitHeader(line):
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				git.create = true
+			case strings.HasPrefix(line, "deleted file mode"):
+				git.delete = true
+			case strings.HasPrefix(line, "rename from"):
+				git.rename = true
+			case strings.HasPrefix(line, "copy from"):
+				git.copy = true
+			case strings.HasPrefix(line, "GIT binary patch"), strings.HasPrefix(line, "Binary files"):
+				return nil, fmt.Errorf("binary patches are not supported (line %d)", p.i+1)
+			}
+			p.i++
+
+		case strings.HasPrefix(line, "--- ") && strings.HasPrefix(next, "+++ "):
+			fp := git
+			if fp == nil || len(fp.hunks) != 0 {
+				fp = &filePatch{}
+				patches = append(patches, fp)
+			}
+			git = nil
+			fp.oldName, fp.oldStamp = parseName(line[4:])
+			fp.newName, fp.newStamp = parseName(next[4:])
+			p.i += 2
+			if err := p.unifiedHunks(fp); err != nil {
+				return nil, err
+			}
+
+		case strings.HasPrefix(line, "*** ") && strings.HasPrefix(next, "--- "):
+			fp := git
+			if fp == nil || len(fp.hunks) != 0 {
+				fp = &filePatch{}
+				patches = append(patches, fp)
+			}
+			git = nil
+			fp.oldName, fp.oldStamp = parseName(line[4:])
+			fp.newName, fp.newStamp = parseName(next[4:])
+			p.i += 2
+			if err := p.contextHunks(fp); err != nil {
+				return nil, err
+			}
+
+		default:
+			// Leading text, or the end of a git header block.
+			if git != nil && !isGitHeader(line) {
+				git = nil
+			}
+			p.i++
+		}
+	}
+
+	// A side is missing if it is named /dev/null or, as in the output of
+	// diff -N, if its timestamp is the epoch. A side that is merely empty
+	// is an empty file, which is removed only with -E.
+	for _, fp := range patches {
+		if fp.oldName == "/dev/null" || isEpoch(fp.oldStamp) {
+			fp.create = true
+		}
+		if fp.newName == "/dev/null" || isEpoch(fp.newStamp) {
+			fp.delete = true
+		}
+	}
+	return patches, nil
+}
+
+// isEpoch reports whether stamp, the timestamp of a header line, is the
+// Unix epoch, which diff -N gives to absent files. Stamps without a time
+// zone are in local time, as diff writes them.
+func isEpoch(stamp string) bool {
+	stamp = strings.TrimSpace(stamp)
+	for _, layout := range []string{
+		"2006-01-02 15:04:05 -0700", // unified, including any fraction of a second
+		"2006-01-02 15:04:05",
+		"Mon Jan _2 15:04:05 2006", // context
+	} {
+		if t, err := time.ParseInLocation(layout, stamp, time.Local); err == nil {
+			return t.Unix() == 0
+		}
+	}
+	return false
+}
+
+// emptySide reports whether hunks consist of a single hunk whose side
+// without the lines of kind omit is empty and starts at line zero.
+func emptySide(hunks []*hunk, omit byte) bool {
+	if len(hunks) != 1 {
+		return false
+	}
+	h := hunks[0]
+	start := h.oldStart
+	if omit == '-' {
+		start = h.newStart
+	}
+	return start == 0 && len(h.side(omit)) == 0
+}
+
+// isGitHeader reports whether line is one of git's extended header lines.
+func isGitHeader(line string) bool {
+	for _, prefix := range []string{
+		"old mode ", "new mode ", "deleted file mode ", "new file mode ",
+		"copy from ", "copy to ", "rename from ", "rename to ",
+		"similarity index ", "dissimilarity index ", "index ",
+		"GIT binary patch", "Binary files ",
+	} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGitNames splits the operands of a "diff --git" line. The names are
+// assumed to be of equal length unless they are separated by " b/".
+func parseGitNames(s string) (string, string) {
+	if i := strings.Index(s, " b/"); i >= 0 && strings.HasPrefix(s, "a/") {
+		return s[:i], s[i+1:]
+	}
+	if n := len(s); n%2 == 1 && s[n/2] == ' ' {
+		return s[:n/2], s[n/2+1:]
+	}
+	name, rest, _ := strings.Cut(s, " ")
+	return name, rest
+}
+
+// parseName splits the operand of a header line into a file name and the
+// timestamp, if any, that follows it.
+func parseName(s string) (name, stamp string) {
+	s = strings.TrimSuffix(s, "\n")
+	name = s
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		name = s[:i]
+	}
+	name = strings.TrimRight(name, " ")
+	return name, s[len(name):]
+}
+
+// unifiedHunks parses the hunks of a unified diff.
+func (p *parser) unifiedHunks(fp *filePatch) error {
+	for p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], "@@ ") {
+		if len(fp.hunks) == 0 {
+			fp.line = p.i + 1
+		}
+		h, oldCount, newCount, err := parseUnifiedHeader(p.lines[p.i])
+		if err != nil {
+			return fmt.Errorf("line %d: %v", p.i+1, err)
+		}
+		p.i++
+		for oldCount > 0 || newCount > 0 {
+			if p.i == len(p.lines) {
+				return fmt.Errorf("line %d: unexpected end of patch in hunk", p.i)
+			}
+			text := p.lines[p.i]
+			kind := byte(' ')
+			if text == "\n" {
+				// Some tools strip the space from empty context lines.
+			} else {
+				kind, text = text[0], text[1:]
+			}
+			switch kind {
+			case ' ':
+				oldCount--
+				newCount--
+			case '-':
+				oldCount--
+			case '+':
+				newCount--
+			default:
+				return fmt.Errorf("line %d: malformed hunk line %q", p.i+1, strings.TrimSuffix(p.lines[p.i], "\n"))
+			}
+			if oldCount < 0 || newCount < 0 {
+				return fmt.Errorf("line %d: hunk is longer than its header says", p.i+1)
+			}
+			h.lines = append(h.lines, hunkLine{kind, text})
+			p.i++
+			p.noNewline(&h.lines[len(h.lines)-1].text)
+		}
+		fp.hunks = append(fp.hunks, h)
+	}
+	return nil
+}
+
+// noNewline consumes a "\ No newline at end of file" marker, if present,
+// and removes the newline from the preceding line.
+func (p *parser) noNewline(text *string) {
+	if p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], `\`) {
+		*text = strings.TrimSuffix(*text, "\n")
+		p.i++
+	}
+}
+
+// parseUnifiedHeader parses a hunk header of the form "@@ -l,s +l,s @@".
+func parseUnifiedHeader(line string) (*hunk, int, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || fields[1][0] != '-' || fields[2][0] != '+' {
+		return nil, 0, 0, fmt.Errorf("malformed hunk header %q", strings.TrimSuffix(line, "\n"))
+	}
+	oldStart, oldCount, err1 := parseRange(fields[1][1:])
+	newStart, newCount, err2 := parseRange(fields[2][1:])
+	if err1 != nil || err2 != nil {
+		return nil, 0, 0, fmt.Errorf("malformed hunk header %q", strings.TrimSuffix(line, "\n"))
+	}
+	return &hunk{oldStart: oldStart, newStart: newStart}, oldCount, newCount, nil
+}
+
+// parseRange parses a unified range "l,s" or "l".
+func parseRange(s string) (start, count int, err error) {
+	startStr, countStr, ok := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if ok {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// A contextLine is a line of one side of a context diff hunk.
+type contextLine struct {
+	mark byte // ' ', '-', '+' or '!'
+	text string
+}
+
+// contextHunks parses the hunks of a context diff, converting each to the
+// equivalent unified hunk.
+func (p *parser) contextHunks(fp *filePatch) error {
+	for p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], "***************") {
+		p.i++
+		if len(fp.hunks) == 0 {
+			fp.line = p.i + 1
+		}
+		oldStart, old, err := p.contextSide("*** ", " ****")
+		if err != nil {
+			return err
+		}
+		newStart, new, err := p.contextSide("--- ", " ----")
+		if err != nil {
+			return err
+		}
+
+		// A side that has no changes may be omitted; it consists of the
+		// context lines of the other side.
+		if old == nil {
+			old = contextOnly(new)
+		}
+		if new == nil {
+			new = contextOnly(old)
+		}
+
+		h := &hunk{oldStart: oldStart, newStart: newStart}
+		for i, j := 0, 0; i < len(old) || j < len(new); {
+			switch {
+			case i < len(old) && old[i].mark == '-':
+				h.lines = append(h.lines, hunkLine{'-', old[i].text})
+				i++
+			case j < len(new) && new[j].mark == '+':
+				h.lines = append(h.lines, hunkLine{'+', new[j].text})
+				j++
+			case i < len(old) && old[i].mark == '!' || j < len(new) && new[j].mark == '!':
+				for ; i < len(old) && old[i].mark == '!'; i++ {
+					h.lines = append(h.lines, hunkLine{'-', old[i].text})
+				}
+				for ; j < len(new) && new[j].mark == '!'; j++ {
+					h.lines = append(h.lines, hunkLine{'+', new[j].text})
+				}
+			case i < len(old) && j < len(new):
+				h.lines = append(h.lines, hunkLine{' ', old[i].text})
+				i, j = i+1, j+1
+			default:
+				return fmt.Errorf("line %d: context diff hunk sides do not match", p.i)
+			}
+		}
+		fp.hunks = append(fp.hunks, h)
+	}
+	return nil
+}
+
+// contextOnly returns the context lines of one side of a hunk.
+func contextOnly(lines []contextLine) []contextLine {
+	var res []contextLine
+	for _, l := range lines {
+		if l.mark == ' ' {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// contextSide parses one side of a context diff hunk: a header such as
+// "*** 1,5 ****" followed by its lines, if any. It returns the one-based
+// first line of the side, or the line before an empty side, and its lines,
+// which are nil if they were omitted.
+func (p *parser) contextSide(prefix, suffix string) (int, []contextLine, error) {
+	if p.i == len(p.lines) {
+		return 0, nil, fmt.Errorf("line %d: unexpected end of patch in hunk", p.i)
+	}
+	header := strings.TrimSuffix(p.lines[p.i], "\n")
+	if !strings.HasPrefix(header, prefix) || !strings.HasSuffix(header, suffix) {
+		return 0, nil, fmt.Errorf("line %d: malformed context hunk header %q", p.i+1, header)
+	}
+	first, last := 0, 0
+	r := header[len(prefix) : len(header)-len(suffix)]
+	firstStr, lastStr, ok := strings.Cut(r, ",")
+	first, err := strconv.Atoi(firstStr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("line %d: malformed context hunk header %q", p.i+1, header)
+	}
+	last = first
+	if ok {
+		if last, err = strconv.Atoi(lastStr); err != nil {
+			return 0, nil, fmt.Errorf("line %d: malformed context hunk header %q", p.i+1, header)
+		}
+	}
+	p.i++
+
+	count := last - first + 1
+	if !ok && first == 0 {
+		count = 0
+	}
+	var lines []contextLine
+	for len(lines) < count && p.i < len(p.lines) {
+		text := p.lines[p.i]
+		if len(text) < 2 || !strings.ContainsRune(" -+!", rune(text[0])) || text[1] != ' ' {
+			break // the side was omitted
+		}
+		lines = append(lines, contextLine{text[0], text[2:]})
+		p.i++
+		p.noNewline(&lines[len(lines)-1].text)
+	}
+	if lines != nil && len(lines) != count {
+		return 0, nil, fmt.Errorf("line %d: context hunk has %d lines, want %d", p.i+1, len(lines), count)
+	}
+	return first, lines, nil
+}
@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pgavlin/text"
+)
+
+// ToContext applies the edits to content and returns a diff in the context
+// format of "diff -c", with contextLines unchanged lines before and after
+// each change. The old and new labels are the names of the content and
+// result files. It returns an error if the edits are inconsistent; see
+// ApplyEdits.
+func ToContext[S text.String](oldLabel, newLabel string, content S, edits []Edit[S], contextLines int) (string, error) {
+	u, err := toUnified(oldLabel, newLabel, NewLineIndex(content), edits, contextLines)
+	if err != nil {
+		return "", err
+	}
+	return u.contextString(), nil
+}
+
+// contextString converts a unified diff to the textual form of a context
+// diff. Each hunk lists the affected lines of the old file followed by those
+// of the new file; a side without changes lists no lines. Lines are marked
+// with '-' if deleted, '+' if inserted, and '!' if part of a run of lines
+// that were replaced.
+func (u unified) contextString() string {
+	if len(u.Hunks) == 0 {
+		return ""
+	}
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "*** %s\n", u.From)
+	fmt.Fprintf(b, "--- %s\n", u.To)
+	for _, h := range u.Hunks {
+		// Mark the runs of changed lines that contain both deletions and
+		// insertions.
+		changed := make([]bool, len(h.Lines))
+		for i := 0; i < len(h.Lines); {
+			if h.Lines[i].Kind == Equal {
+				i++
+				continue
+			}
+			j, dels, ins := i, false, false
+			for ; j < len(h.Lines) && h.Lines[j].Kind != Equal; j++ {
+				dels = dels || h.Lines[j].Kind == Delete
+				ins = ins || h.Lines[j].Kind == Insert
+			}
+			for ; i < j; i++ {
+				changed[i] = dels && ins
+			}
+		}
+
+		fromCount, toCount, dels, ins := 0, 0, false, false
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Delete:
+				fromCount++
+				dels = true
+			case Insert:
+				toCount++
+				ins = true
+			default:
+				fromCount++
+				toCount++
+			}
+		}
+
+		fmt.Fprint(b, "***************\n")
+		fmt.Fprintf(b, "*** %s ****\n", contextRange(h.FromLine, fromCount))
+		if dels {
+			writeContextLines(b, h.Lines, changed, Delete, '-')
+		}
+		fmt.Fprintf(b, "--- %s ----\n", contextRange(h.ToLine, toCount))
+		if ins {
+			writeContextLines(b, h.Lines, changed, Insert, '+')
+		}
+	}
+	return b.String()
+}
+
+// contextRange formats the range of count lines starting at line as GNU
+// diff does: an empty range is numbered by the line that precedes it.
+func contextRange(line, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprint(line - 1)
+	case 1:
+		return fmt.Sprint(line)
+	default:
+		return fmt.Sprintf("%d,%d", line, line+count-1)
+	}
+}
+
+// writeContextLines writes the lines of one side of a context hunk: the
+// equal lines, and the changed lines of the given kind.
+func writeContextLines(b *strings.Builder, lines []line, changed []bool, kind OpKind, mark byte) {
+	for i, l := range lines {
+		switch {
+		case l.Kind == Equal:
+			fmt.Fprintf(b, "  %s", l.Content)
+		case l.Kind != kind:
+			continue
+		case changed[i]:
+			fmt.Fprintf(b, "! %s", l.Content)
+		default:
+			fmt.Fprintf(b, "%c %s", mark, l.Content)
+		}
+		if !strings.HasSuffix(l.Content, "\n") {
+			fmt.Fprintf(b, "\n\\ No newline at end of file\n")
+		}
+	}
+}
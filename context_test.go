@@ -0,0 +1,195 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff"
+)
+
+// The expected outputs below were produced by GNU diff.
+const (
+	contextOld = "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	contextNew = "a\nB\nc\nd\ne\nf\ng\nh\nj\nk"
+)
+
+func TestToContext(t *testing.T) {
+	for _, test := range []struct {
+		old, new string
+		want     string
+	}{
+		{contextOld, contextNew, `*** x
+--- y
+***************
+*** 1,10 ****
+  a
+! b
+  c
+  d
+  e
+  f
+  g
+  h
+- i
+  j
+--- 1,10 ----
+  a
+! B
+  c
+  d
+  e
+  f
+  g
+  h
+  j
++ k
+\ No newline at end of file
+`},
+		{contextOld, "", `*** x
+--- y
+***************
+*** 1,10 ****
+` + strings.Join(strings.SplitAfter("- "+strings.ReplaceAll(strings.TrimSuffix(contextOld, "\n"), "\n", "\n- "), "\n"), "") + `
+--- 0 ----
+`},
+		{contextOld, contextOld, ""},
+	} {
+		got, err := diff.ToContext("x", "y", test.old, diff.Lines(test.old, test.new), diff.DefaultContextLines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("ToContext(%q, %q):\ngot:\n%s\nwant:\n%s", test.old, test.new, got, test.want)
+		}
+	}
+}
+
+func TestToUnifiedContext(t *testing.T) {
+	for _, test := range []struct {
+		old, new string
+		context  int
+		want     string
+	}{
+		{contextOld, contextNew, 1, `--- x
++++ y
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -8,3 +8,3 @@
+ h
+-i
+ j
++k
+\ No newline at end of file
+`},
+		{contextOld, contextNew, 0, `--- x
++++ y
+@@ -2 +2 @@
+-b
++B
+@@ -9 +8,0 @@
+-i
+@@ -10,0 +10 @@
++k
+\ No newline at end of file
+`},
+		{"", "a\nb\n", 0, `--- x
++++ y
+@@ -0,0 +1,2 @@
++a
++b
+`},
+	} {
+		got, err := diff.ToUnifiedContext("x", "y", test.old, diff.Lines(test.old, test.new), test.context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("ToUnifiedContext(%q, %q, %d):\ngot:\n%s\nwant:\n%s", test.old, test.new, test.context, got, test.want)
+		}
+	}
+}
+
+// TestToUnifiedHunks pins how ToUnified groups line edits into hunks and
+// numbers empty ranges. Each edit keeps its own lines, so an unchanged line
+// between two edits is shown as context, and two edits share a hunk only if
+// their contexts meet.
+func TestToUnifiedHunks(t *testing.T) {
+	const ten = "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	for _, test := range []struct {
+		old, new string
+		context  int
+		want     string
+	}{
+		{"bd\nda\nb\n", "b\ncb\nd\na\nbb\nba\n", 3, `@@ -1,3 +1,6 @@
+-bd
+-da
+ b
++cb
++d
++a
++bb
++ba
+`},
+		{ten, "1\nX\n3\n4\n5\n6\n7\n8\nY\n10\n", 3, `@@ -1,10 +1,10 @@
+ 1
+-2
++X
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
++Y
+ 10
+`},
+		{ten, "1\nX\n3\n4\n5\n6\n7\n8\nY\n10\n", 2, `@@ -1,4 +1,4 @@
+ 1
+-2
++X
+ 3
+ 4
+@@ -7,4 +7,4 @@
+ 7
+ 8
+-9
++Y
+ 10
+`},
+		{ten, "1\n2\n3\nnew\n4\n5\n6\n8\n9\n10\n", 0, `@@ -3,0 +4 @@
++new
+@@ -7 +7,0 @@
+-7
+`},
+		{ten, "1\n2\n3\nnew\n4\n5\n6\n8\n9\n10\n", 1, `@@ -3,2 +3,3 @@
+ 3
++new
+ 4
+@@ -6,3 +7,2 @@
+ 6
+-7
+ 8
+`},
+		{"a\nb\nc\n", "a\nc\n", 0, "@@ -2 +1,0 @@\n-b\n"},
+		{"a\nb\nc\n", "a\nb\nx\nc\n", 0, "@@ -2,0 +3 @@\n+x\n"},
+	} {
+		edits := diff.Lines(test.old, test.new)
+		got, err := diff.ToUnifiedContext("x", "y", test.old, edits, test.context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "--- x\n+++ y\n" + test.want
+		if got != want {
+			t.Errorf("ToUnifiedContext(%q, %q, %d):\ngot:\n%s\nwant:\n%s", test.old, test.new, test.context, got, want)
+		}
+		if test.context == diff.DefaultContextLines {
+			if got, err := diff.ToUnified("x", "y", test.old, edits); err != nil || got != want {
+				t.Errorf("ToUnified(%q, %q) = %q, %v; want %q", test.old, test.new, got, err, want)
+			}
+		}
+	}
+}
@@ -187,6 +187,59 @@ expand:
 	return append(expanded, expandEdit(prev, src)), nil // flush final edit
 }
 
+// alignEdits is like lineEdits, but expands edits only as far as
+// necessary: an edit that already replaces whole lines with whole lines is
+// left alone, and adjacent edits are not merged. The resulting edits are
+// sorted, and each either ends at the start of a line or at EOF.
+func alignEdits[S text.String](src S, edits []Edit[S]) ([]Edit[S], error) {
+	edits, _, err := Validate(len(src), edits)
+	if err != nil {
+		return nil, err
+	}
+
+	aligned := make([]Edit[S], 0, len(edits))
+	for i := 0; i < len(edits); {
+		// Expand the start of the group of edits to the start of its line.
+		start := text.LastIndexByte(src[:edits[i].Start], '\n') + 1
+
+		// Accumulate the replacement text of the group, expanding its end
+		// to the end of a line, and absorbing any edits that the expansion
+		// reaches.
+		var parts []S
+		complete := true // the replacement is empty or ends in a newline
+		add := func(s S) {
+			if len(s) > 0 {
+				parts = append(parts, s)
+				complete = s[len(s)-1] == '\n'
+			}
+		}
+		cursor, end := start, start
+		for {
+			edit := edits[i]
+			add(src[cursor:edit.Start])
+			add(edit.New)
+			cursor, i = edit.End, i+1
+
+			end = cursor
+			if !complete || cursor > 0 && src[cursor-1] != '\n' {
+				if nl := text.IndexByte(src[cursor:], '\n'); nl < 0 {
+					end = len(src)
+				} else {
+					end = cursor + nl + 1
+				}
+			}
+			// A group that ends within the last line absorbs any
+			// insertions at EOF.
+			if i == len(edits) || edits[i].Start >= end && (end == 0 || src[end-1] == '\n') {
+				break
+			}
+		}
+		add(src[cursor:end])
+		aligned = append(aligned, Edit[S]{Start: start, End: end, New: text.Join(parts, "")})
+	}
+	return aligned, nil
+}
+
 // expandEdit returns edit expanded to complete whole lines.
 func expandEdit[S text.String](edit Edit[S], src S) Edit[S] {
 	// Expand start left to start of line.
@@ -6,6 +6,7 @@ package diff_test
 
 import (
 	"bytes"
+	"fmt"
 	"math/rand"
 	"os"
 	"os/exec"
@@ -218,6 +219,28 @@ func TestLineEdits(t *testing.T) {
 	}
 }
 
+func TestAlignEdits(t *testing.T) {
+	rand.Seed(1)
+	for i := 0; i < 1000; i++ {
+		a, b := randstr("ab\n", 16), randstr("abc\n", 16)
+		edits := diff.Text(a, b)
+		got, err := diff.AlignEdits(a, edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if patched, err := diff.Apply(a, got); err != nil || patched != b {
+			t.Fatalf("AlignEdits(%q, %v) = %v, which gives %q, %v; want %q", a, edits, got, patched, err, b)
+		}
+		for j, e := range got {
+			if j > 0 && e.Start < got[j-1].End ||
+				e.Start > 0 && a[e.Start-1] != '\n' ||
+				e.End < len(a) && (e.End > 0 && a[e.End-1] != '\n' || len(e.New) > 0 && e.New[len(e.New)-1] != '\n') {
+				t.Fatalf("AlignEdits(%q, %v): misaligned edit %v", a, edits, e)
+			}
+		}
+	}
+}
+
 func TestToUnified(t *testing.T) {
 	testenv.NeedsTool(t, "patch")
 	for _, tc := range difftest.TestCases {
@@ -260,6 +283,34 @@ func TestToUnified(t *testing.T) {
 	}
 }
 
+// TestToUnifiedWholeFile checks that the empty side of a created or
+// deleted file is numbered as GNU diff numbers it, by the line before it,
+// which patch requires to recognize the creation or deletion.
+func TestToUnifiedWholeFile(t *testing.T) {
+	for _, tc := range []struct {
+		name, old, new, want string
+	}{
+		{"delete", "a\nb\n", "", "@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{"delete one", "a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"create", "", "a\nb\n", "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"create one", "", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"empty", "a\nb\n", "a\nb\n", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := diff.ToUnified("old", "new", tc.old, diff.Lines(tc.old, tc.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.want != "" {
+				tc.want = "--- old\n+++ new\n" + tc.want
+			}
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestRegressionOld001(t *testing.T) {
 	a := "// Copyright 2019 The Go Authors. All rights reserved.\n// Use of this source code is governed by a BSD-style\n// license that can be found in the LICENSE file.\n\npackage diff_test\n\nimport (\n\t\"fmt\"\n\t\"math/rand\"\n\t\"strings\"\n\t\"testing\"\n\n\t\"golang.org/x/tools/gopls/internal/lsp/diff\"\n\t\"golang.org/x/tools/internal/diff/difftest\"\n\t\"golang.org/x/tools/gopls/internal/span\"\n)\n"
 
@@ -304,3 +355,36 @@ func randstr(s string, n int) string {
 	}
 	return string(x)
 }
+
+func TestLinesParallel(t *testing.T) {
+	before, after := largeEdit(rand.New(rand.NewSource(1)), 50000)
+	for _, workers := range []int{0, 1, 3} {
+		edits := diff.LinesParallel(before, after, workers)
+		if got, err := diff.Apply(before, edits); err != nil || got != after {
+			t.Errorf("workers=%d: applying the edits gives an error %v or the wrong text", workers, err)
+		}
+	}
+}
+
+// largeEdit returns a text of n lines, many of them repeated, and a copy of
+// it with about one line in a hundred inserted, deleted or replaced.
+func largeEdit(rng *rand.Rand, n int) (before, after string) {
+	var b, a strings.Builder
+	for i := 0; i < n; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		if rng.Intn(3) == 0 {
+			line = "}\n"
+		}
+		b.WriteString(line)
+		switch rng.Intn(300) {
+		case 0: // delete
+		case 1: // insert
+			fmt.Fprintf(&a, "new %d\n%s", i, line)
+		case 2: // replace
+			a.WriteString("}\n")
+		default:
+			a.WriteString(line)
+		}
+	}
+	return b.String(), a.String()
+}
@@ -0,0 +1,136 @@
+// Package diffassert provides test assertions that report mismatched texts
+// as unified diffs.
+//
+// Rather than printing both texts in full, a failed assertion prints only
+// the lines that differ and some context around them, numbered by their
+// lines in the wanted and actual texts:
+//
+//	mismatch (-want +got):
+//	--- want
+//	+++ got
+//	@@ -2,3 +2,3 @@
+//	2 2  b
+//	3   -c·
+//	  3 +c
+//	4 4  d
+//
+// Whitespace in changed lines is made visible: spaces are shown as "·",
+// tabs as "→" and carriage returns as "␍". Very long diffs are truncated.
+package diffassert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/text"
+)
+
+// MaxLines is the number of lines of a diff after which Report truncates it.
+const MaxLines = 200
+
+// TB is the part of testing.TB that the assertions use.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertEqual reports an error to t if got differs from want, and returns
+// whether they are equal. The error shows a unified diff of the texts; see
+// Report.
+func AssertEqual[S text.String](t TB, want, got S) bool {
+	t.Helper()
+	if string(want) == string(got) {
+		return true
+	}
+	t.Errorf("mismatch (-want +got):\n%s", Report("want", "got", want, got))
+	return false
+}
+
+// Report returns a unified diff of two texts whose lines are numbered and
+// whose changed lines show their whitespace, as a test failure message
+// would. A diff longer than MaxLines is truncated. Report returns "" if the
+// texts are equal.
+func Report[S text.String](oldLabel, newLabel string, old, new S) string {
+	u, err := diff.ToUnified(oldLabel, newLabel, old, diff.Lines(old, new))
+	if err != nil {
+		// Can't happen: the edits were computed from the text.
+		panic(err)
+	}
+	if u == "" {
+		return ""
+	}
+
+	// Number the lines wide enough for the last line of either text.
+	last := strings.Count(string(old), "\n") + 1
+	if n := strings.Count(string(new), "\n") + 1; n > last {
+		last = n
+	}
+	width := len(strconv.Itoa(last))
+	blank := strings.Repeat(" ", width)
+	num := func(n int) string {
+		s := strconv.Itoa(n)
+		return strings.Repeat(" ", width-len(s)) + s
+	}
+
+	var b strings.Builder
+	lines := strings.SplitAfter(u, "\n")
+	lines = lines[:len(lines)-1] // the diff ends with a newline
+	oldLine, newLine := 0, 0
+	for i, l := range lines {
+		if i == MaxLines {
+			fmt.Fprintf(&b, "... %d more lines of diff omitted\n", len(lines)-i)
+			break
+		}
+		switch {
+		case i < 2 || strings.HasPrefix(l, `\`):
+			b.WriteString(l)
+		case strings.HasPrefix(l, "@@"):
+			oldLine, newLine = hunkStart(l)
+			b.WriteString(l)
+		case strings.HasPrefix(l, "-"):
+			fmt.Fprintf(&b, "%s %s -%s", num(oldLine), blank, visible(l[1:]))
+			oldLine++
+		case strings.HasPrefix(l, "+"):
+			fmt.Fprintf(&b, "%s %s +%s", blank, num(newLine), visible(l[1:]))
+			newLine++
+		default:
+			fmt.Fprintf(&b, "%s %s  %s", num(oldLine), num(newLine), l[1:])
+			oldLine, newLine = oldLine+1, newLine+1
+		}
+	}
+	return b.String()
+}
+
+// hunkStart returns the numbers of the first old and new lines of the hunk
+// with the header h.
+func hunkStart(h string) (oldLine, newLine int) {
+	var oldRange, newRange string
+	fmt.Sscanf(h, "@@ -%s +%s @@", &oldRange, &newRange)
+	return rangeStart(oldRange), rangeStart(newRange)
+}
+
+// rangeStart returns the number of the first line of a unified diff range.
+// An empty range is numbered by the line that precedes it.
+func rangeStart(r string) int {
+	start, count, ok := strings.Cut(r, ",")
+	n, _ := strconv.Atoi(start)
+	if ok && count == "0" {
+		n++
+	}
+	return n
+}
+
+var whitespace = strings.NewReplacer(" ", "·", "\t", "→", "\r", "␍")
+
+// visible returns a line with its whitespace, other than its final newline,
+// made visible.
+func visible(l string) string {
+	l, nl := strings.CutSuffix(l, "\n")
+	l = whitespace.Replace(l)
+	if nl {
+		l += "\n"
+	}
+	return l
+}
@@ -0,0 +1,92 @@
+package diffassert_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/diffassert"
+)
+
+// recorder records the errors reported by an assertion.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertEqual(t *testing.T) {
+	var r recorder
+	if !diffassert.AssertEqual(&r, "a\nb\n", "a\nb\n") || len(r.errors) != 0 {
+		t.Fatalf("equal texts reported %q", r.errors)
+	}
+	if diffassert.AssertEqual(&r, []byte("a\nb\n"), []byte("a\nc\n")) || len(r.errors) != 1 {
+		t.Fatalf("unequal texts reported %q", r.errors)
+	}
+	const want = "mismatch (-want +got):\n--- want\n+++ got\n@@ -1,2 +1,2 @@\n1 1  a\n2   -b\n  2 +c\n"
+	if r.errors[0] != want {
+		t.Errorf("got error:\n%s\nwant:\n%s", r.errors[0], want)
+	}
+}
+
+func TestReport(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\n", new: "a\n",
+		},
+		{
+			name: "whitespace",
+			old:  "a\nb c\nd\n", new: "a\nb\tc \r\nd\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n1 1  a\n2   -b·c\n  2 +b→c·␍\n3 3  d\n",
+		},
+		{
+			name: "missing newline",
+			old:  "a\nb", new: "a\nb\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n1 1  a\n2   -b\n\\ No newline at end of file\n  2 +b\n",
+		},
+		{
+			name: "line numbers",
+			old:  lines(1, 20), new: lines(1, 5) + lines(7, 20) + "new\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -3,7 +3,6 @@\n 3  3  line 3\n 4  4  line 4\n 5  5  line 5\n 6    -line·6\n 7  6  line 7\n 8  7  line 8\n 9  8  line 9\n" +
+				"@@ -18,3 +17,4 @@\n18 17  line 18\n19 18  line 19\n20 19  line 20\n   20 +new\n",
+		},
+		{
+			name: "empty",
+			old:  "", new: "a\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1 @@\n  1 +a\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := diffassert.Report("old", "new", test.old, test.new); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReportTruncated(t *testing.T) {
+	got := diffassert.Report("old", "new", lines(1, 1000), "")
+	lines := strings.Split(got, "\n")
+	if len(lines) != diffassert.MaxLines+2 || lines[diffassert.MaxLines] != "... 803 more lines of diff omitted" {
+		t.Errorf("got %d lines ending in %q", len(lines), lines[len(lines)-2:])
+	}
+}
+
+// lines returns the lines "line start" to "line end", inclusive.
+func lines(start, end int) string {
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"sync"
+
+	"github.com/pgavlin/diff/lcs"
+	"github.com/pgavlin/text"
+	"github.com/pgavlin/text/utf8"
+)
+
+// A Differ computes the same edits as Text, Lines and Binary, but reuses
+// its memory from one diff to the next, so that a program that diffs many
+// texts allocates little more than the edits that it returns. The zero
+// Differ is ready to use.
+//
+// A Differ must not be used by more than one goroutine at a time. A
+// DifferPool shares Differs between goroutines.
+type Differ[S text.String] struct {
+	scratch                     lcs.Scratch
+	beforeRunes, afterRunes     []rune
+	beforeLines, afterLines     []S
+	beforeOffsets, afterOffsets []int
+}
+
+// Text computes the differences between two texts, like the Text function.
+func (d *Differ[S]) Text(before, after S) []Edit[S] {
+	return d.diffText(before, after, false)
+}
+
+// Binary computes the differences between two texts, like the Binary
+// function.
+func (d *Differ[S]) Binary(before, after S) []Edit[S] {
+	return d.diffText(before, after, true)
+}
+
+func (d *Differ[S]) diffText(before, after S, binary bool) []Edit[S] {
+	if text.Equal(before, after) {
+		return nil // common case
+	}
+
+	if binary || isASCII(before) && isASCII(after) {
+		return asciiEdits(lcs.DiffTextScratch(before, after, &d.scratch), after)
+	}
+	d.beforeRunes, d.afterRunes = appendRunes(d.beforeRunes[:0], before), appendRunes(d.afterRunes[:0], after)
+	return runeEdits[S](lcs.DiffRunesScratch(d.beforeRunes, d.afterRunes, &d.scratch), d.beforeRunes, d.afterRunes)
+}
+
+// Lines computes the line differences between two texts, like the Lines
+// function.
+func (d *Differ[S]) Lines(before, after S) []Edit[S] {
+	d.beforeLines, d.afterLines = appendLines(d.beforeLines[:0], before), appendLines(d.afterLines[:0], after)
+	d.beforeOffsets, d.afterOffsets = appendLineOffsets(d.beforeOffsets[:0], d.beforeLines), appendLineOffsets(d.afterOffsets[:0], d.afterLines)
+
+	diffs := lcs.DiffLinesScratch(d.beforeLines, d.afterLines, &d.scratch)
+	edits := offsetDiffEdits(diffs, d.beforeOffsets, d.afterOffsets, after)
+
+	// Don't retain the texts.
+	var zero S
+	for i := range d.beforeLines {
+		d.beforeLines[i] = zero
+	}
+	for i := range d.afterLines {
+		d.afterLines[i] = zero
+	}
+	return edits
+}
+
+// appendRunes appends the runes of s to dst, like text.ToRunes.
+func appendRunes[S text.String](dst []rune, s S) []rune {
+	for len(s) > 0 {
+		r, size := rune(s[0]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(s)
+		}
+		dst = append(dst, r)
+		s = s[size:]
+	}
+	return dst
+}
+
+// appendLines appends the lines of t to dst, like splitLines.
+func appendLines[S text.String](dst []S, t S) []S {
+	for len(t) > 0 {
+		i := text.IndexByte(t, '\n')
+		if i < 0 {
+			return append(dst, t)
+		}
+		dst, t = append(dst, t[:i+1]), t[i+1:]
+	}
+	return dst
+}
+
+// A DifferPool is a pool of Differs that may be used by many goroutines at
+// once. The zero DifferPool is ready to use.
+type DifferPool[S text.String] struct {
+	pool sync.Pool
+}
+
+// Get returns a Differ from the pool, or a new Differ if the pool is empty.
+func (p *DifferPool[S]) Get() *Differ[S] {
+	if d, ok := p.pool.Get().(*Differ[S]); ok {
+		return d
+	}
+	return new(Differ[S])
+}
+
+// Put returns a Differ to the pool. The Differ must not be used after it is
+// returned.
+func (p *DifferPool[S]) Put(d *Differ[S]) {
+	p.pool.Put(d)
+}
+
+// Text computes the differences between two texts with a Differ from the
+// pool.
+func (p *DifferPool[S]) Text(before, after S) []Edit[S] {
+	d := p.Get()
+	defer p.Put(d)
+	return d.Text(before, after)
+}
+
+// Lines computes the line differences between two texts with a Differ from
+// the pool.
+func (p *DifferPool[S]) Lines(before, after S) []Edit[S] {
+	d := p.Get()
+	defer p.Put(d)
+	return d.Lines(before, after)
+}
+
+// Binary computes the differences between two texts with a Differ from the
+// pool.
+func (p *DifferPool[S]) Binary(before, after S) []Edit[S] {
+	d := p.Get()
+	defer p.Put(d)
+	return d.Binary(before, after)
+}
@@ -0,0 +1,75 @@
+package diff_test
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+)
+
+// equalEdits reports whether two lists of edits are equal, treating nil and
+// empty lists as equal.
+func equalEdits[S ~string | ~[]byte](a, b []diff.Edit[S]) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+func TestDiffer(t *testing.T) {
+	var d diff.Differ[string]
+	var bd diff.Differ[[]byte]
+	for _, tc := range difftest.TestCases {
+		if got, want := d.Text(tc.In, tc.Out), diff.Text(tc.In, tc.Out); !equalEdits(got, want) {
+			t.Errorf("%s: Text: got %v, want %v", tc.Name, got, want)
+		}
+		if got, want := d.Lines(tc.In, tc.Out), diff.Lines(tc.In, tc.Out); !equalEdits(got, want) {
+			t.Errorf("%s: Lines: got %v, want %v", tc.Name, got, want)
+		}
+		if got, want := d.Binary(tc.In, tc.Out), diff.Binary(tc.In, tc.Out); !equalEdits(got, want) {
+			t.Errorf("%s: Binary: got %v, want %v", tc.Name, got, want)
+		}
+
+		in, out := []byte(tc.In), []byte(tc.Out)
+		if got, want := bd.Text(in, out), diff.Text(in, out); !equalEdits(got, want) {
+			t.Errorf("%s: Text of bytes: got %v, want %v", tc.Name, got, want)
+		}
+		if got, want := bd.Lines(in, out), diff.Lines(in, out); !equalEdits(got, want) {
+			t.Errorf("%s: Lines of bytes: got %v, want %v", tc.Name, got, want)
+		}
+	}
+}
+
+func TestDifferAllocs(t *testing.T) {
+	before := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 20)
+	after := strings.Replace(before, "lazy", "sleepy", 3)
+
+	// Only the edits are allocated.
+	var d diff.Differ[string]
+	if n := testing.AllocsPerRun(100, func() { d.Text(before, after) }); n > 1 {
+		t.Errorf("Text allocates %v times per run", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { d.Lines(before, after) }); n > 1 {
+		t.Errorf("Lines allocates %v times per run", n)
+	}
+}
+
+func TestDifferPool(t *testing.T) {
+	var pool diff.DifferPool[string]
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, tc := range difftest.TestCases {
+				if got, want := pool.Lines(tc.In, tc.Out), diff.Lines(tc.In, tc.Out); !equalEdits(got, want) {
+					t.Errorf("%s: Lines: got %v, want %v", tc.Name, got, want)
+				}
+				if got, want := pool.Text(tc.In, tc.Out), diff.Text(tc.In, tc.Out); !equalEdits(got, want) {
+					t.Errorf("%s: Text: got %v, want %v", tc.Name, got, want)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,174 @@
+package difftest
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/myers"
+)
+
+// An Algorithm is a named implementation of a diff algorithm.
+type Algorithm struct {
+	Name    string
+	Compute func(before, after string) []diff.Edit[string]
+}
+
+// Algorithms are the diff algorithms of this module, for comparison by
+// RunCorpus. New algorithms should be added here.
+var Algorithms = []Algorithm{
+	{Name: "lcs", Compute: diff.Text[string, string]},
+	{Name: "lcs-lines", Compute: diff.Lines[string, string]},
+	{Name: "myers", Compute: myers.ComputeEdits[string, string]},
+}
+
+// A Corpus is a sequence of versions of a file, oldest first.
+type Corpus struct {
+	Name     string
+	Versions []Version
+}
+
+// A Version is one version of the file of a Corpus.
+type Version struct {
+	Name string
+	Text string
+}
+
+// LoadCorpus reads a corpus from a zip archive, such as the one built by
+// lcs/git.sh, that holds one version of a file per entry. The entries are
+// numbered in the order of git log, newest first, so the versions are
+// ordered by the decreasing names of their entries.
+func LoadCorpus(path string) (*Corpus, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	c := &Corpus{Name: path}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		text, err := readZipFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		c.Versions = append(c.Versions, Version{Name: f.Name, Text: text})
+	}
+	sort.Slice(c.Versions, func(i, j int) bool { return c.Versions[i].Name > c.Versions[j].Name })
+	return c, nil
+}
+
+func readZipFile(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	var b strings.Builder
+	if _, err := io.Copy(&b, rc); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Metrics measure the diffs that an algorithm computes for the consecutive
+// versions of a corpus. Fewer edits, hunks and changed lines make for
+// smaller and usually more readable diffs.
+type Metrics struct {
+	Algorithm string
+	// Pairs is the number of pairs of versions that were diffed.
+	Pairs int
+	// Edits is the total number of edits.
+	Edits int
+	// Hunks is the total number of hunks of the unified diffs.
+	Hunks int
+	// ChangedLines is the total number of lines inserted and deleted by the
+	// unified diffs.
+	ChangedLines int
+	// Time is the total time spent computing the edits.
+	Time time.Duration
+}
+
+// RunCorpus diffs each pair of consecutive versions of a corpus with each
+// of the algorithms, checks that applying the edits to the earlier version
+// gives the later one, and returns the metrics of each algorithm. It returns
+// an error if an algorithm's edits are wrong.
+func RunCorpus(c *Corpus, algorithms []Algorithm) ([]Metrics, error) {
+	metrics := make([]Metrics, len(algorithms))
+	for i, alg := range algorithms {
+		m := &metrics[i]
+		m.Algorithm = alg.Name
+		for j := 1; j < len(c.Versions); j++ {
+			before, after := c.Versions[j-1], c.Versions[j]
+
+			start := time.Now()
+			edits := alg.Compute(before.Text, after.Text)
+			m.Time += time.Since(start)
+
+			got, err := diff.Apply(before.Text, edits)
+			if err == nil && got != after.Text {
+				err = errors.New("applying the edits gives the wrong text")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: diff of %s and %s: %w", alg.Name, before.Name, after.Name, err)
+			}
+
+			unified, err := diff.ToUnified(before.Name, after.Name, before.Text, edits)
+			if err != nil {
+				return nil, fmt.Errorf("%s: diff of %s and %s: %w", alg.Name, before.Name, after.Name, err)
+			}
+			stat, err := diff.Stat(before.Name, before.Text, edits)
+			if err != nil {
+				return nil, fmt.Errorf("%s: diff of %s and %s: %w", alg.Name, before.Name, after.Name, err)
+			}
+			m.Pairs++
+			m.Edits += len(edits)
+			m.Hunks += strings.Count(unified, "\n@@ ")
+			m.ChangedLines += stat.Insertions + stat.Deletions
+		}
+	}
+	return metrics, nil
+}
+
+// WriteMetrics writes a table of metrics, one algorithm per row.
+func WriteMetrics(w io.Writer, metrics []Metrics) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "algorithm\tpairs\tedits\thunks\tchanged lines\ttime\t\n")
+	for _, m := range metrics {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%v\t\n", m.Algorithm, m.Pairs, m.Edits, m.Hunks, m.ChangedLines, m.Time.Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
+
+// CorpusTest runs the algorithms over the corpus in the zip archive at path,
+// and logs their metrics. It skips t if the archive does not exist, and
+// fails it if an algorithm computes wrong edits.
+func CorpusTest(t *testing.T, path string, algorithms []Algorithm) {
+	t.Helper()
+	c, err := LoadCorpus(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("skipping because %s does not exist", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := RunCorpus(c, algorithms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := WriteMetrics(&b, metrics); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d versions of %s:\n%s", len(c.Versions), c.Name, b.String())
+}
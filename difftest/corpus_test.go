@@ -0,0 +1,98 @@
+package difftest_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+)
+
+// writeCorpus writes a zip archive of versions, named newest first as by
+// lcs/git.sh, in the order of their names.
+func writeCorpus(t *testing.T, versions map[string]string) string {
+	path := filepath.Join(t.TempDir(), "testdata.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range []string{"000001", "000002", "000003"} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(versions[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunCorpus(t *testing.T) {
+	path := writeCorpus(t, map[string]string{
+		"000003": "a\nb\nc\n",
+		"000002": "a\nB\nc\nd\n",
+		"000001": "a\nB\nc\nd\n" + strings.Repeat("x\n", 10) + "e\n",
+	})
+	c, err := difftest.LoadCorpus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Versions) != 3 || c.Versions[0].Name != "000003" || c.Versions[2].Name != "000001" {
+		t.Fatalf("got versions %v", c.Versions)
+	}
+
+	metrics, err := difftest.RunCorpus(c, difftest.Algorithms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range metrics {
+		// Both diffs are single hunks, which change at least 1 deleted and 2
+		// inserted lines, then 11 inserted lines, as history is diffed
+		// forwards. Diffs of characters may change more lines than needed.
+		if m.Pairs != 2 || m.Hunks != 2 || m.ChangedLines < 14 || m.Edits == 0 {
+			t.Errorf("got metrics %+v", m)
+		}
+		if m.Algorithm == "lcs-lines" && m.ChangedLines != 14 {
+			t.Errorf("got metrics %+v, want 14 changed lines", m)
+		}
+	}
+
+	var b strings.Builder
+	if err := difftest.WriteMetrics(&b, metrics[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(b.String(), "\n"); len(lines) != 3 || strings.Join(strings.Fields(lines[1])[:2], " ") != "lcs 2" {
+		t.Errorf("got table:\n%s", b.String())
+	}
+
+	broken := difftest.Algorithm{Name: "broken", Compute: func(before, after string) []diff.Edit[string] { return nil }}
+	if _, err := difftest.RunCorpus(c, []difftest.Algorithm{broken}); err == nil || !strings.HasPrefix(err.Error(), "broken: diff of 000003 and 000002") {
+		t.Errorf("got error %v for wrong edits", err)
+	}
+}
+
+func TestCorpusTest(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		defer func() {
+			if !t.Skipped() {
+				t.Error("missing corpus did not skip the test")
+			}
+		}()
+		difftest.CorpusTest(t, filepath.Join(t.TempDir(), "missing.zip"), difftest.Algorithms)
+	})
+	t.Run("present", func(t *testing.T) {
+		path := writeCorpus(t, map[string]string{"000001": "a\n", "000002": "b\n", "000003": "c\n"})
+		difftest.CorpusTest(t, path, difftest.Algorithms)
+	})
+}
@@ -8,11 +8,11 @@
 package difftest
 
 // There are two kinds of tests, semantic tests, and 'golden data' tests.
-// The semantic tests check that the computed diffs transform the input to
-// the output, and that 'patch' accepts the computed unified diffs.
-// The other tests just check that Edits and LineEdits haven't changed
-// unexpectedly. These fields may need to be changed when the diff algorithm
-// changes.
+// The semantic tests of DiffTest check that the computed diffs transform
+// the input to the output; VerifyPatch checks that 'patch' accepts the
+// computed unified diffs. The other tests just check that Edits and
+// LineEdits haven't changed unexpectedly. These fields may need to be
+// changed when the diff algorithm changes.
 
 import (
 	"testing"
@@ -0,0 +1,204 @@
+package difftest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/text"
+)
+
+// FuzzAlgorithm fuzzes an implementation of a text diff algorithm, using
+// the inputs of TestCases as the seed corpus. For each pair of inputs, it
+// checks that:
+//
+//   - the edits are sorted, do not overlap and lie within the input;
+//   - the edits split no runes of the input;
+//   - applying the edits to the input gives the output; and
+//   - the unified diff of the edits transforms the input into the output,
+//     and is empty only if the input and output are equal.
+//
+// Inputs that are not valid UTF-8 are skipped.
+//
+//	func FuzzDiff(f *testing.F) {
+//		difftest.FuzzAlgorithm(f, mydiff.ComputeEdits[string, string])
+//	}
+func FuzzAlgorithm[S text.String](f *testing.F, compute func(before, after S) []diff.Edit[S]) {
+	for _, test := range TestCases {
+		f.Add(test.In, test.Out)
+	}
+	f.Fuzz(func(t *testing.T, before, after string) {
+		if !utf8.ValidString(before) || !utf8.ValidString(after) {
+			return // inputs must be text
+		}
+		edits := compute(S(before), S(after))
+		if err := checkEdits(before, edits); err != nil {
+			t.Fatalf("diff(%q, %q): %v; edits=%v", before, after, err, edits)
+		}
+
+		got, err := diff.Apply(before, edits)
+		if err != nil {
+			t.Fatalf("diff(%q, %q): Apply failed: %v", before, after, err)
+		}
+		if got != after {
+			t.Fatalf("applying diff(%q, %q) gives %q; edits=%v", before, after, got, edits)
+		}
+
+		unified, err := diff.ToUnified(FileA, FileB, S(before), edits)
+		if err != nil {
+			t.Fatalf("diff(%q, %q): ToUnified failed: %v", before, after, err)
+		}
+		if (unified == "") != (before == after) {
+			t.Fatalf("diff(%q, %q): got unified diff %q", before, after, unified)
+		}
+		got, err = applyUnified(before, unified)
+		if err != nil {
+			t.Fatalf("diff(%q, %q): bad unified diff: %v\n%s", before, after, err, unified)
+		}
+		if got != after {
+			t.Fatalf("applying the unified diff of (%q, %q) gives %q:\n%s", before, after, got, unified)
+		}
+	})
+}
+
+// checkEdits checks that edits are sorted, disjoint, within the bounds of
+// src and aligned with its runes.
+func checkEdits[S text.String](src string, edits []diff.Edit[S]) error {
+	end := 0
+	for i, e := range edits {
+		switch {
+		case e.Start < end:
+			return fmt.Errorf("edit %d (%v) is out of order or overlaps its predecessor", i, e)
+		case e.End < e.Start || e.End > len(src):
+			return fmt.Errorf("edit %d (%v) is out of bounds", i, e)
+		case !runeBoundary(src, e.Start) || !runeBoundary(src, e.End):
+			return fmt.Errorf("edit %d (%v) splits a rune", i, e)
+		case !utf8.ValidString(string(e.New)):
+			return fmt.Errorf("edit %d (%v) inserts invalid UTF-8", i, e)
+		}
+		end = e.End
+	}
+	return nil
+}
+
+// runeBoundary reports whether offset is at the start of a rune of s, or at
+// its end.
+func runeBoundary(s string, offset int) bool {
+	return offset == len(s) || utf8.RuneStart(s[offset])
+}
+
+// applyUnified applies a unified diff of a single file to src.
+func applyUnified(src, unified string) (string, error) {
+	if unified == "" {
+		return src, nil
+	}
+	lines := strings.SplitAfter(unified, "\n")
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "--- ") || !strings.HasPrefix(lines[1], "+++ ") {
+		return "", fmt.Errorf("missing file header")
+	}
+	lines = lines[2 : len(lines)-1] // the diff ends with a newline
+
+	old := strings.SplitAfter(src, "\n")
+	if old[len(old)-1] == "" {
+		old = old[:len(old)-1]
+	}
+	var b strings.Builder
+	pos := 0 // the next line of old to copy
+	for len(lines) > 0 {
+		oldStart, oldCount, newCount, err := parseHunkHeader(lines[0])
+		if err != nil {
+			return "", err
+		}
+		lines = lines[1:]
+		if oldStart < pos || oldStart > len(old) {
+			return "", fmt.Errorf("hunk at line %d is out of order or out of bounds", oldStart+1)
+		}
+		for _, l := range old[pos:oldStart] {
+			b.WriteString(l)
+		}
+		pos = oldStart
+
+		for oldCount > 0 || newCount > 0 {
+			if len(lines) == 0 {
+				return "", fmt.Errorf("hunk at line %d is too short", oldStart+1)
+			}
+			l := lines[0]
+			lines = lines[1:]
+			if len(lines) > 0 && strings.HasPrefix(lines[0], `\`) {
+				l = strings.TrimSuffix(l, "\n")
+				lines = lines[1:]
+			}
+			op, text := l[0], l[1:]
+			if op == ' ' || op == '-' {
+				if oldCount == 0 || pos == len(old) || old[pos] != text {
+					return "", fmt.Errorf("hunk line %q does not match line %d %q", l, pos+1, lineAt(old, pos))
+				}
+				pos++
+				oldCount--
+			}
+			if op == ' ' || op == '+' {
+				if newCount == 0 {
+					return "", fmt.Errorf("hunk at line %d is too long", oldStart+1)
+				}
+				b.WriteString(text)
+				newCount--
+			}
+			if op != ' ' && op != '-' && op != '+' {
+				return "", fmt.Errorf("bad hunk line %q", l)
+			}
+		}
+	}
+	for _, l := range old[pos:] {
+		b.WriteString(l)
+	}
+	return b.String(), nil
+}
+
+// parseHunkHeader parses a hunk header of a unified diff, and returns the
+// zero-based index of its first old line and its numbers of old and new
+// lines.
+func parseHunkHeader(h string) (oldStart, oldCount, newCount int, err error) {
+	oldRange, rest, ok1 := strings.Cut(strings.TrimPrefix(h, "@@ -"), " +")
+	newRange, _, ok2 := strings.Cut(rest, " @@")
+	if !strings.HasPrefix(h, "@@ -") || !ok1 || !ok2 {
+		return 0, 0, 0, fmt.Errorf("bad hunk header %q", h)
+	}
+	start, oldCount, err := parseRange(oldRange)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	_, newCount, err = parseRange(newRange)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	// An empty range is numbered by the line that precedes it.
+	if oldCount > 0 {
+		start--
+	}
+	return start, oldCount, newCount, nil
+}
+
+// parseRange parses a range "start[,count]" of a hunk header.
+func parseRange(r string) (start, count int, err error) {
+	s, c, ok := strings.Cut(r, ",")
+	count = 1
+	if ok {
+		if count, err = strconv.Atoi(c); err != nil {
+			return 0, 0, fmt.Errorf("bad range %q", r)
+		}
+	}
+	if start, err = strconv.Atoi(s); err != nil {
+		return 0, 0, fmt.Errorf("bad range %q", r)
+	}
+	return start, count, nil
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package difftest_test
+
+import (
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+)
+
+// $ go test -fuzz=FuzzText ./difftest
+func FuzzText(f *testing.F) {
+	difftest.FuzzAlgorithm(f, diff.Text[string, string])
+}
+
+// $ go test -fuzz=FuzzLines ./difftest
+func FuzzLines(f *testing.F) {
+	difftest.FuzzAlgorithm(f, diff.Lines[string, string])
+}
+
+// $ go test -fuzz=FuzzBytes ./difftest
+func FuzzBytes(f *testing.F) {
+	difftest.FuzzAlgorithm(f, diff.Text[[]byte, []byte])
+}
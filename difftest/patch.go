@@ -0,0 +1,89 @@
+package difftest
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/testenv"
+	"github.com/pgavlin/text"
+)
+
+// VerifyPatch checks that the system's tools accept the unified diffs that
+// an implementation of a diff algorithm computes for TestCases. For each
+// case, it writes the input and the unified diff of the computed edits to a
+// temporary directory, applies the diff with patch, and compares the result
+// with the expected output. If git is present, it does the same with git
+// apply.
+//
+// VerifyPatch skips the checks with patch if testenv.NeedsTool does.
+func VerifyPatch[S text.String](t *testing.T, compute func(before, after S) []diff.Edit[S]) {
+	t.Run("patch", func(t *testing.T) {
+		testenv.NeedsTool(t, "patch")
+		verifyPatch(t, compute, func(dir, patch string) *exec.Cmd {
+			return exec.Command("patch", "-s", "-f", "-o", filepath.Join(dir, FileB), "-i", patch, filepath.Join(dir, FileA))
+		})
+	})
+	t.Run("git", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skipf("skipping because git is not available: %v", err)
+		}
+		verifyPatch(t, compute, func(dir, patch string) *exec.Cmd {
+			// git apply patches FileA in place. It works outside of a
+			// repository, so keep it from finding one above the directory.
+			cmd := exec.Command("git", "apply", "-p0", "--unidiff-zero", patch)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GIT_CEILING_DIRECTORIES="+filepath.Dir(dir))
+			return cmd
+		})
+	})
+}
+
+// verifyPatch runs a tool that applies the unified diffs of TestCases. The
+// tool is given the directory that holds FileA and the path of the diff, and
+// writes the patched output to FileB in the directory or patches FileA in
+// place.
+func verifyPatch[S text.String](t *testing.T, compute func(before, after S) []diff.Edit[S], tool func(dir, patch string) *exec.Cmd) {
+	for _, test := range TestCases {
+		t.Run(test.Name, func(t *testing.T) {
+			edits := compute(S(test.In), S(test.Out))
+			unified, err := diff.ToUnified(FileA, FileA, S(test.In), edits)
+			if err != nil {
+				t.Fatalf("ToUnified: %v", err)
+			}
+			if unified == "" {
+				return // there is nothing to apply
+			}
+
+			dir := t.TempDir()
+			patch := filepath.Join(dir, "patch.diff")
+			if err := os.WriteFile(filepath.Join(dir, FileA), []byte(test.In), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(patch, []byte(unified), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			cmd := tool(dir, patch)
+			var stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stderr, &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("%v failed: %v\n%s\ndiff:\n%s", cmd.Args, err, stderr.Bytes(), unified)
+			}
+
+			out := filepath.Join(dir, FileB)
+			if _, err := os.Stat(out); os.IsNotExist(err) {
+				out = filepath.Join(dir, FileA)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.Out {
+				t.Errorf("%v: got patched:\n%q\nfrom diff:\n%s\nexpected:\n%q", cmd.Args, got, unified, test.Out)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package difftest_test
+
+import (
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+)
+
+func TestVerifyPatch(t *testing.T) {
+	difftest.VerifyPatch(t, diff.Text[string, string])
+}
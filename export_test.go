@@ -11,3 +11,7 @@ import "github.com/pgavlin/text"
 func LineEdits[S text.String](src S, edits []Edit[S]) ([]Edit[S], error) {
 	return lineEdits(src, edits)
 }
+
+func AlignEdits[S text.String](src S, edits []Edit[S]) ([]Edit[S], error) {
+	return alignEdits(src, edits)
+}
@@ -0,0 +1,374 @@
+// Package godiff compares two versions of a Go source file declaration by
+// declaration.
+//
+// Rather than diffing the files line by line, it matches the top-level
+// functions, methods, types, variables and constants of the two versions by
+// name, and reports which were added, removed, modified or moved. The lines
+// of each modified declaration are then diffed on their own, so that a
+// change summary can say which declarations changed and how.
+package godiff
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/lcs"
+)
+
+// DeclKind is the kind of a top-level declaration.
+type DeclKind int
+
+const (
+	// Func is the kind of a function declaration.
+	Func DeclKind = iota
+	// Method is the kind of a method declaration.
+	Method
+	// Type is the kind of a type declaration.
+	Type
+	// Var is the kind of a variable declaration.
+	Var
+	// Const is the kind of a constant declaration.
+	Const
+)
+
+// String returns the Go keyword, or "method", for a DeclKind.
+func (k DeclKind) String() string {
+	switch k {
+	case Func:
+		return "func"
+	case Method:
+		return "method"
+	case Type:
+		return "type"
+	case Var:
+		return "var"
+	case Const:
+		return "const"
+	default:
+		return "unknown"
+	}
+}
+
+// A Decl is a top-level declaration of a Go file. Each spec of a grouped
+// type, var or const declaration is a separate Decl.
+type Decl struct {
+	Kind DeclKind
+	// Name is the declared name. The names of methods are qualified by the
+	// base type of their receivers, as in "T.M", and a spec that declares
+	// several variables or constants is named by the comma-separated list
+	// of their names.
+	Name string
+	// Span is the extent in the source of the lines that hold the
+	// declaration, including its doc comment and the final newline. For a
+	// spec of a grouped declaration, it covers only the lines of the spec.
+	Span diff.Span
+	// Line is the one-based line on which Span begins.
+	Line int
+	// Text is the source text of Span.
+	Text string
+}
+
+// Decls returns the top-level declarations of a Go source file, other than
+// its imports, in the order in which they appear.
+func Decls(filename string, src []byte) ([]Decl, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	tf := fset.File(f.Pos())
+
+	var decls []Decl
+	add := func(kind DeclKind, name string, doc *ast.CommentGroup, node ast.Node) {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		span := diff.Span{Start: tf.Offset(tf.LineStart(tf.Line(start))), End: tf.Offset(node.End())}
+		if i := bytes.IndexByte(src[span.End:], '\n'); i >= 0 {
+			span.End += i + 1
+		} else {
+			span.End = len(src)
+		}
+		decls = append(decls, Decl{
+			Kind: kind,
+			Name: name,
+			Span: span,
+			Line: tf.Line(start),
+			Text: string(src[span.Start:span.End]),
+		})
+	}
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				add(Func, d.Name.Name, d.Doc, d)
+			} else {
+				add(Method, receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, d.Doc, d)
+			}
+		case *ast.GenDecl:
+			var kind DeclKind
+			switch d.Tok {
+			case token.TYPE:
+				kind = Type
+			case token.VAR:
+				kind = Var
+			case token.CONST:
+				kind = Const
+			default:
+				continue
+			}
+			for _, spec := range d.Specs {
+				var name string
+				var doc *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					name, doc = s.Name.Name, s.Doc
+				case *ast.ValueSpec:
+					names := make([]string, len(s.Names))
+					for i, n := range s.Names {
+						names[i] = n.Name
+					}
+					name, doc = strings.Join(names, ", "), s.Doc
+				}
+				if d.Lparen.IsValid() {
+					add(kind, name, doc, spec)
+				} else {
+					add(kind, name, d.Doc, d)
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// receiverName returns the name of the base type of a method's receiver.
+func receiverName(t ast.Expr) string {
+	for {
+		switch e := t.(type) {
+		case *ast.StarExpr:
+			t = e.X
+		case *ast.ParenExpr:
+			t = e.X
+		case *ast.IndexExpr:
+			t = e.X
+		case *ast.IndexListExpr:
+			t = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return "?"
+		}
+	}
+}
+
+// ChangeKind describes how a declaration differs between two files.
+type ChangeKind int
+
+const (
+	// Added is the kind of a declaration that is present only in the new
+	// file.
+	Added ChangeKind = iota
+	// Removed is the kind of a declaration that is present only in the old
+	// file.
+	Removed
+	// Modified is the kind of a declaration whose text differs between the
+	// files.
+	Modified
+	// Moved is the kind of a declaration whose text is unchanged but whose
+	// position relative to the other declarations differs.
+	Moved
+)
+
+// String returns a human readable representation of a ChangeKind.
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	case Moved:
+		return "moved"
+	default:
+		return "unknown"
+	}
+}
+
+// A Change describes a declaration that differs between two files.
+type Change struct {
+	Kind ChangeKind
+	// Old and New are the declaration in the old and new files. Old is nil
+	// for an added declaration, and New for a removed one.
+	Old, New *Decl
+	// Moved reports whether the declaration's position relative to the
+	// other declarations differs. It is true for moved declarations, and
+	// may be true for modified ones.
+	Moved bool
+	// Edits transform Old.Text into New.Text. They are nil unless the
+	// declaration is modified.
+	Edits []diff.Edit[string]
+}
+
+// Decl returns the newest version of the declaration.
+func (c *Change) Decl() *Decl {
+	if c.New != nil {
+		return c.New
+	}
+	return c.Old
+}
+
+// String returns a one-line description of the change, such as
+// "modified func F".
+func (c *Change) String() string {
+	d := c.Decl()
+	s := fmt.Sprintf("%v %v %s", c.Kind, d.Kind, d.Name)
+	if c.Kind == Modified && c.Moved {
+		s += " (moved)"
+	}
+	if c.Old != nil && c.New != nil && c.Old.Kind != c.New.Kind {
+		s += fmt.Sprintf(" (was %v)", c.Old.Kind)
+	}
+	return s
+}
+
+// Diff compares the declarations of two versions of a Go source file, and
+// returns the changes in the order of the new file. Each removed declaration
+// follows the change, if any, to the declaration that preceded it in the old
+// file. Declarations are matched by name, so a renamed declaration is
+// reported as removed and added.
+func Diff(filename string, old, new []byte) ([]Change, error) {
+	oldDecls, err := Decls(filename, old)
+	if err != nil {
+		return nil, err
+	}
+	newDecls, err := Decls(filename, new)
+	if err != nil {
+		return nil, err
+	}
+	return DiffDecls(oldDecls, newDecls), nil
+}
+
+// DiffDecls is like Diff, but compares declarations that have already been
+// parsed.
+func DiffDecls(old, new []Decl) []Change {
+	oldKeys, newKeys := keys(old), keys(new)
+	oldIndex := make(map[string]int, len(old))
+	for i, k := range oldKeys {
+		oldIndex[k] = i
+	}
+	newIndex := make(map[string]int, len(new))
+	for i, k := range newKeys {
+		newIndex[k] = i
+	}
+
+	// The declarations in both files that are out of order are moved.
+	var oldCommon, newCommon []string
+	for _, k := range oldKeys {
+		if _, ok := newIndex[k]; ok {
+			oldCommon = append(oldCommon, k)
+		}
+	}
+	for _, k := range newKeys {
+		if _, ok := oldIndex[k]; ok {
+			newCommon = append(newCommon, k)
+		}
+	}
+	moved := map[string]bool{}
+	for _, d := range lcs.DiffSlices(oldCommon, newCommon) {
+		for _, k := range newCommon[d.ReplStart:d.ReplEnd] {
+			moved[k] = true
+		}
+	}
+
+	// Removed declarations follow the one that preceded them in the old
+	// file, or come first if none did.
+	removedAfter := map[int][]int{} // from an index in old, or -1, to the indices of removed declarations
+	anchor := -1
+	for i, k := range oldKeys {
+		if _, ok := newIndex[k]; ok {
+			anchor = i
+		} else {
+			removedAfter[anchor] = append(removedAfter[anchor], i)
+		}
+	}
+
+	var changes []Change
+	removed := func(anchor int) {
+		for _, i := range removedAfter[anchor] {
+			changes = append(changes, Change{Kind: Removed, Old: &old[i]})
+		}
+	}
+	removed(-1)
+	for j, k := range newKeys {
+		i, ok := oldIndex[k]
+		if !ok {
+			changes = append(changes, Change{Kind: Added, New: &new[j]})
+			continue
+		}
+		c := Change{Kind: Moved, Old: &old[i], New: &new[j], Moved: moved[k]}
+		if c.Old.Text != c.New.Text || c.Old.Kind != c.New.Kind {
+			c.Kind, c.Edits = Modified, diff.Lines(c.Old.Text, c.New.Text)
+		}
+		if c.Kind == Modified || c.Moved {
+			changes = append(changes, c)
+		}
+		removed(i)
+	}
+	return changes
+}
+
+// keys returns the keys by which declarations are matched: their names,
+// followed by "#n" for the nth repetition of a name, as for init functions
+// or blank variables.
+func keys(decls []Decl) []string {
+	keys := make([]string, len(decls))
+	seen := map[string]int{}
+	for i, d := range decls {
+		n := seen[d.Name]
+		seen[d.Name] = n + 1
+		keys[i] = d.Name
+		if n > 0 {
+			keys[i] += fmt.Sprintf("#%d", n+1)
+		}
+	}
+	return keys
+}
+
+// Unified returns a unified diff of a modified declaration, whose line
+// numbers are relative to the start of the declaration. It returns "" for
+// other changes.
+func (c *Change) Unified() string {
+	if c.Kind != Modified {
+		return ""
+	}
+	label := func(d *Decl) string { return fmt.Sprintf("%v %s:%d", d.Kind, d.Name, d.Line) }
+	u, err := diff.ToUnified(label(c.Old), label(c.New), c.Old.Text, c.Edits)
+	if err != nil {
+		// Can't happen: the edits were computed from the text.
+		panic(err)
+	}
+	return u
+}
+
+// WriteReport writes a summary of changes: a line for each change, followed
+// for modified declarations by a unified diff of the declaration.
+func WriteReport(w io.Writer, changes []Change) error {
+	for i := range changes {
+		c := &changes[i]
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, c.Unified()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package godiff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/godiff"
+)
+
+const oldSrc = `package p
+
+import "fmt"
+
+// A is a constant.
+const A = 1
+
+type (
+	// T is a type.
+	T struct{ x int }
+	U int
+)
+
+func init() {}
+
+func init() { fmt.Println() }
+
+// F does things.
+func F() {
+	fmt.Println("one")
+	fmt.Println("two")
+}
+
+func (t *T) M() {}
+
+func (u U) N() {}
+
+var x, y = 1, 2
+
+func G() {}
+`
+
+const newSrc = `package p
+
+import "fmt"
+
+func G() {}
+
+// A is a constant.
+const A = 1
+
+type (
+	// T is a type.
+	T struct{ x, y int }
+	V int
+)
+
+func init() {}
+
+func init() { fmt.Println("init") }
+
+// F does things.
+func F() {
+	fmt.Println("one")
+	fmt.Println("2")
+}
+
+func (t T) M() {}
+
+var x, y = 1, 2
+`
+
+func TestDecls(t *testing.T) {
+	decls, err := godiff.Decls("p.go", []byte(oldSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range decls {
+		got = append(got, d.Kind.String()+" "+d.Name)
+	}
+	want := []string{"const A", "type T", "type U", "func init", "func init", "func F", "method T.M", "method U.N", "var x, y", "func G"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got decls %q, want %q", got, want)
+	}
+
+	// Spans cover whole lines, including doc comments, and only the spec of
+	// a grouped declaration.
+	if want := "// A is a constant.\nconst A = 1\n"; decls[0].Text != want || decls[0].Line != 5 {
+		t.Errorf("got %q on line %d, want %q on line 5", decls[0].Text, decls[0].Line, want)
+	}
+	if want := "\t// T is a type.\n\tT struct{ x int }\n"; decls[1].Text != want {
+		t.Errorf("got %q, want %q", decls[1].Text, want)
+	}
+	if got := oldSrc[decls[5].Span.Start:decls[5].Span.End]; got != decls[5].Text {
+		t.Errorf("span %v is %q, want %q", decls[5].Span, got, decls[5].Text)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := godiff.Diff("p.go", []byte(oldSrc), []byte(newSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := godiff.WriteReport(&b, changes); err != nil {
+		t.Fatal(err)
+	}
+	const want = `moved func G
+modified type T
+--- type T:9
++++ type T:11
+@@ -1,2 +1,2 @@
+ 	// T is a type.
+-	T struct{ x int }
++	T struct{ x, y int }
+removed type U
+added type V
+modified func init
+--- func init:16
++++ func init:18
+@@ -1 +1 @@
+-func init() { fmt.Println() }
++func init() { fmt.Println("init") }
+modified func F
+--- func F:18
++++ func F:20
+@@ -1,5 +1,5 @@
+ // F does things.
+ func F() {
+ 	fmt.Println("one")
+-	fmt.Println("two")
++	fmt.Println("2")
+ }
+modified method T.M
+--- method T.M:24
++++ method T.M:26
+@@ -1 +1 @@
+-func (t *T) M() {}
++func (t T) M() {}
+removed method U.N
+`
+	if got := b.String(); got != want {
+		t.Errorf("got report:\n%s\nwant:\n%s", got, want)
+	}
+
+	if changes, err := godiff.Diff("p.go", []byte(oldSrc), []byte(oldSrc)); err != nil || len(changes) != 0 {
+		t.Errorf("Diff of identical files = %v, %v, want none", changes, err)
+	}
+	if _, err := godiff.Diff("p.go", []byte(oldSrc), []byte("package p\nfunc (")); err == nil {
+		t.Error("Diff accepted invalid source")
+	}
+}
+
+func TestDiffKindChange(t *testing.T) {
+	changes, err := godiff.Diff("p.go", []byte("package p\n\nvar X = 1\n"), []byte("package p\n\nconst X = 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].String() != "modified const X (was var)" {
+		t.Errorf("got %v, want a single modified const", changes)
+	}
+}
@@ -0,0 +1,134 @@
+// Package golden compares test output with golden files.
+//
+// A golden file holds the expected output of a test, in the testdata
+// directory of the package under test. When output differs from its golden
+// file, the test fails with a unified diff of the two; see
+// [github.com/pgavlin/diff/diffassert]. Running the tests with the -update
+// flag rewrites the golden files with the current output instead:
+//
+//	go test ./... -update
+//
+// The package defines the flag when it is initialized, unless a package
+// initialized before it has already defined a flag of that name, in which
+// case the assertions use that flag. Package initializers run before those
+// of the packages that import them, so a test package that uses golden
+// files must not define -update itself: the flag package would panic on the
+// second definition. Such a package can read the flag with [Updating].
+//
+// Output can be normalized before it is compared or written, for example to
+// replace timestamps or temporary paths with fixed text.
+package golden
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pgavlin/diff/diffassert"
+	"github.com/pgavlin/text"
+)
+
+func init() {
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "rewrite golden files")
+	}
+}
+
+// Updating reports whether the -update flag is set.
+func Updating() bool {
+	f := flag.Lookup("update")
+	return f != nil && f.Value.String() == "true"
+}
+
+// TB is the part of testing.TB that the assertions use.
+type TB interface {
+	Helper()
+	Name() string
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Options controls where golden files are kept and how output is
+// normalized.
+type Options struct {
+	// Dir is the directory that holds the golden files. It defaults to
+	// "testdata".
+	Dir string
+	// Normalize rewrites output, in order, before it is compared with or
+	// written to a golden file.
+	Normalize []func(string) string
+}
+
+// Replace returns a normalization that replaces the matches of the regular
+// expression pattern with repl, which may refer to submatches as
+// regexp.ReplaceAllString allows.
+func Replace(pattern, repl string) func(string) string {
+	re := regexp.MustCompile(pattern)
+	return func(s string) string { return re.ReplaceAllString(s, repl) }
+}
+
+// Path returns the path of the golden file named name for the test t:
+// Dir/<test name>.golden, or Dir/<test name>.<name>.golden if name is not
+// empty. The files of subtests are kept in a directory named for the parent
+// test.
+func (opts *Options) Path(t TB, name string) string {
+	dir := "testdata"
+	if opts != nil && opts.Dir != "" {
+		dir = opts.Dir
+	}
+	file := t.Name()
+	if name != "" {
+		file += "." + name
+	}
+	return filepath.Join(dir, filepath.FromSlash(file)+".golden")
+}
+
+// Assert compares got with the golden file of the test t, and reports an
+// error to t if they differ. It is equivalent to AssertNamed with an empty
+// name.
+func Assert[S text.String](t TB, got S, opts *Options) bool {
+	t.Helper()
+	return AssertNamed(t, "", got, opts)
+}
+
+// AssertNamed compares got with the golden file named name of the test t,
+// so that a test can check several outputs. It reports an error to t if
+// they differ, and returns whether they are equal. If the -update flag is
+// set, it writes got to the golden file instead. See Options.Path.
+func AssertNamed[S text.String](t TB, name string, got S, opts *Options) bool {
+	t.Helper()
+	output := string(got)
+	if opts != nil {
+		for _, normalize := range opts.Normalize {
+			output = normalize(output)
+		}
+	}
+
+	path := opts.Path(t, name)
+	if Updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("missing golden file %s; run the test with -update to create it", path)
+			return false
+		}
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if string(want) == output {
+		return true
+	}
+	t.Errorf("output differs from %s (-want +got); run the test with -update to accept it:\n%s", path, diffassert.Report(path, "got", string(want), output))
+	return false
+}
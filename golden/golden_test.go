@@ -0,0 +1,99 @@
+package golden_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/golden"
+)
+
+// recorder records the errors reported by an assertion.
+type recorder struct {
+	name   string
+	errors []string
+}
+
+func (r *recorder) Helper()      {}
+func (r *recorder) Name() string { return r.name }
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+// setUpdate sets the -update flag for the duration of a test.
+func setUpdate(t *testing.T, value string) {
+	old := flag.Lookup("update").Value.String()
+	if err := flag.Set("update", value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { flag.Set("update", old) })
+}
+
+func TestUpdating(t *testing.T) {
+	if f := flag.Lookup("update"); f == nil || f.DefValue != "false" {
+		t.Fatalf("golden defined -update as %v", f)
+	}
+	setUpdate(t, "true")
+	if !golden.Updating() {
+		t.Error("Updating() = false with -update set")
+	}
+}
+
+func TestPath(t *testing.T) {
+	r := &recorder{name: "TestX/sub_case"}
+	if got, want := (*golden.Options)(nil).Path(r, ""), filepath.Join("testdata", "TestX", "sub_case.golden"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := (&golden.Options{Dir: "gold"}).Path(r, "stderr"), filepath.Join("gold", "TestX", "sub_case.stderr.golden"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	opts := &golden.Options{
+		Dir:       t.TempDir(),
+		Normalize: []func(string) string{golden.Replace(`\d{2}:\d{2}:\d{2}`, "HH:MM:SS")},
+	}
+	r := &recorder{name: "TestOutput/case"}
+
+	// Missing files are reported.
+	if golden.Assert(r, "out\n", opts) || len(r.errors) != 1 || !strings.Contains(r.errors[0], "missing golden file") {
+		t.Fatalf("missing file reported %q", r.errors)
+	}
+
+	// Updating writes normalized output.
+	setUpdate(t, "true")
+	r.errors = nil
+	if !golden.Assert(r, "started at 12:34:56\nline\n", opts) || !golden.AssertNamed(r, "stderr", []byte("warning\n"), opts) || len(r.errors) != 0 {
+		t.Fatalf("update reported %q", r.errors)
+	}
+	data, err := os.ReadFile(opts.Path(r, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "started at HH:MM:SS\nline\n"; got != want {
+		t.Errorf("golden file holds %q, want %q", got, want)
+	}
+
+	// Output is compared after normalization.
+	setUpdate(t, "false")
+	if !golden.Assert(r, "started at 01:02:03\nline\n", opts) || !golden.AssertNamed(r, "stderr", "warning\n", opts) || len(r.errors) != 0 {
+		t.Fatalf("matching output reported %q", r.errors)
+	}
+
+	// Differences are reported as diffs.
+	if golden.AssertNamed(r, "stderr", "error\n", opts) || len(r.errors) != 1 {
+		t.Fatalf("differing output reported %q", r.errors)
+	}
+	if want := "@@ -1 +1 @@\n1   -warning\n  1 +error\n"; !strings.HasSuffix(r.errors[0], want) {
+		t.Errorf("got error:\n%s\nwant a diff ending in:\n%s", r.errors[0], want)
+	}
+}
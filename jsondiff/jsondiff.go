@@ -0,0 +1,239 @@
+// Package jsondiff computes structural differences between JSON documents.
+//
+// Unlike a line diff, a structural diff is insensitive to formatting and to
+// the order of object members. Differences are expressed either as JSON
+// Patch documents (RFC 6902), which are lists of operations addressed by
+// JSON Pointers (RFC 6901), or as JSON Merge Patch documents (RFC 7386),
+// which mirror the shape of the document they change.
+//
+// Functions that take documents as bytes decode them with
+// json.Decoder.UseNumber, so that numbers survive a round trip exactly.
+// Functions that take decoded values accept the values produced by
+// encoding/json when decoding into an interface: nil, bool, float64 or
+// json.Number, string, []any and map[string]any. Numbers are compared by
+// value, so 1, 1.0 and 1e0 are equal.
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decode decodes a single JSON document.
+func decode(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value")
+	}
+	return v, nil
+}
+
+// encode encodes v compactly, without escaping HTML characters.
+func encode(v any) ([]byte, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// equal reports whether two decoded JSON values are deeply equal.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		if b, ok := b.(json.Number); ok && a == b {
+			return true
+		}
+	}
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x.Cmp(y) == 0
+	}
+	return a == b
+}
+
+// number returns the exact value of a decoded JSON number.
+func number(v any) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(v))
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(v) == nil {
+			return nil, false
+		}
+		return r, true
+	}
+	return nil, false
+}
+
+// comparer compares decoded JSON values for lcs.DiffAnySlices.
+type comparer struct{}
+
+func (comparer) Equal(a, b any) bool { return equal(a, b) }
+
+// clone returns a deep copy of a decoded JSON value.
+func clone(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = clone(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = clone(e)
+		}
+		return c
+	}
+	return v
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// appendPointer returns the JSON Pointer to the member or element token of
+// the value at pointer.
+func appendPointer(pointer, token string) string {
+	return pointer + "/" + pointerEscaper.Replace(token)
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = pointerUnescaper.Replace(t)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses a reference token as an index into an array, which must
+// be less than limit.
+func arrayIndex(token string, limit int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || token[0] == '+' || len(token) > 1 && token[0] == '0' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i >= limit {
+		return 0, fmt.Errorf("array index %d out of range [0, %d)", i, limit)
+	}
+	return i, nil
+}
+
+// child returns the member or element of v named by token.
+func child(v any, token string) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		c, ok := v[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", token)
+		}
+		return c, nil
+	case []any:
+		i, err := arrayIndex(token, len(v))
+		if err != nil {
+			return nil, err
+		}
+		return v[i], nil
+	}
+	return nil, fmt.Errorf("cannot index %s with %q", kind(v), token)
+}
+
+// get returns the value at path in doc.
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		var err error
+		if doc, err = child(doc, token); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// update calls f with the object or array that contains the value at path,
+// which must not be empty, and the last token of path, and replaces that
+// container with the one f returns. It returns the updated document.
+func update(doc any, path []string, f func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return f(doc, path[0])
+	}
+	c, err := child(doc, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if c, err = update(c, path[1:], f); err != nil {
+		return nil, err
+	}
+	switch doc := doc.(type) {
+	case map[string]any:
+		doc[path[0]] = c
+	case []any:
+		i, _ := arrayIndex(path[0], len(doc))
+		doc[i] = c
+	}
+	return doc, nil
+}
+
+// kind returns the name of the JSON type of a decoded value.
+func kind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
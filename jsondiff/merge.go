@@ -0,0 +1,301 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/diff/lcs"
+)
+
+// Missing stands for an object member that is absent on one side of a
+// Conflict.
+type Missing struct{}
+
+// A Conflict is a location at which both sides of a merge changed the base
+// document in different ways.
+//
+// For a conflict within an object, or between changes to a single array
+// element, Path is a JSON Pointer to the value and Base, Ours and Theirs are
+// its values, or Missing{} on a side where it is absent. For a conflict
+// between changes to a run of array elements, Path is the pointer to the
+// array, and Base, Ours and Theirs are the conflicting runs of elements, as
+// []any.
+type Conflict struct {
+	Path               string
+	Base, Ours, Theirs any
+}
+
+// A Strategy says how to resolve a conflict.
+type Strategy int
+
+const (
+	// Report leaves the conflict unresolved: the merged document contains
+	// our side of it and the conflict is returned to the caller.
+	Report Strategy = iota
+	// PreferOurs resolves the conflict in favor of our side.
+	PreferOurs
+	// PreferTheirs resolves the conflict in favor of their side.
+	PreferTheirs
+)
+
+// String returns a human readable representation of a Strategy.
+func (s Strategy) String() string {
+	switch s {
+	case Report:
+		return "report"
+	case PreferOurs:
+		return "ours"
+	case PreferTheirs:
+		return "theirs"
+	default:
+		return "unknown"
+	}
+}
+
+// MergeOptions controls a three-way merge.
+type MergeOptions struct {
+	// Strategy resolves conflicts that Paths does not cover.
+	Strategy Strategy
+	// Paths maps JSON Pointers to the strategies for conflicts at or below
+	// them. The longest matching pointer applies.
+	Paths map[string]Strategy
+}
+
+// strategy returns the strategy for a conflict at path.
+func (opts *MergeOptions) strategy(path string) Strategy {
+	if opts == nil {
+		return Report
+	}
+	s, best := opts.Strategy, -1
+	for p, ps := range opts.Paths {
+		if len(p) > best && (path == p || strings.HasPrefix(path, p+"/")) {
+			s, best = ps, len(p)
+		}
+	}
+	return s
+}
+
+// MergeJSON merges the changes that lead from the JSON document base to ours
+// and to theirs, and returns the compactly encoded result along with any
+// conflicts that opts did not resolve. A nil opts reports every conflict.
+// See MergeValues.
+func MergeJSON(base, ours, theirs []byte, opts *MergeOptions) ([]byte, []Conflict, error) {
+	var docs [3]any
+	for i, doc := range [][]byte{base, ours, theirs} {
+		v, err := decode(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s document: %w", [...]string{"base", "our", "their"}[i], err)
+		}
+		docs[i] = v
+	}
+	merged, conflicts := MergeValues(docs[0], docs[1], docs[2], opts)
+	result, err := encode(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, conflicts, nil
+}
+
+// MergeValues merges the changes that lead from the decoded JSON value base
+// to ours and to theirs, and returns the result along with any conflicts
+// that opts did not resolve. The result may share values with the inputs.
+//
+// A value that only one side changed takes that side's value. The members of
+// objects are merged independently, so that changes to different members
+// never conflict; a member that both sides added as objects is merged as if
+// it had been empty. Arrays are aligned with their base by their longest
+// common subsequences of equal elements, and changes to different runs of
+// elements are combined. If both sides replaced the same single element,
+// the replacements are merged recursively; other overlapping changes
+// conflict, as do differing changes to any other value.
+func MergeValues(base, ours, theirs any, opts *MergeOptions) (any, []Conflict) {
+	m := merger{opts: opts}
+	result := m.merge("", base, ours, theirs)
+	return result, m.conflicts
+}
+
+// A merger accumulates the conflicts of a merge.
+type merger struct {
+	opts      *MergeOptions
+	conflicts []Conflict
+}
+
+// conflict resolves a conflict and returns the resolution.
+func (m *merger) conflict(path string, base, ours, theirs any) any {
+	switch m.opts.strategy(path) {
+	case PreferOurs:
+		return ours
+	case PreferTheirs:
+		return theirs
+	}
+	m.conflicts = append(m.conflicts, Conflict{Path: path, Base: base, Ours: ours, Theirs: theirs})
+	return ours
+}
+
+// merge merges the value at path. Absent values are represented by
+// Missing{}.
+func (m *merger) merge(path string, base, ours, theirs any) any {
+	switch {
+	case equal(ours, theirs), equal(base, theirs):
+		return ours
+	case equal(base, ours):
+		return theirs
+	}
+
+	if o, ok := ours.(map[string]any); ok {
+		if t, ok := theirs.(map[string]any); ok {
+			if _, ok := base.(Missing); ok {
+				base = map[string]any{}
+			}
+			if b, ok := base.(map[string]any); ok {
+				return m.object(path, b, o, t)
+			}
+		}
+	}
+	if b, ok := base.([]any); ok {
+		if o, ok := ours.([]any); ok {
+			if t, ok := theirs.([]any); ok {
+				return m.array(path, b, o, t)
+			}
+		}
+	}
+	return m.conflict(path, base, ours, theirs)
+}
+
+// object merges the members of three objects.
+func (m *merger) object(path string, base, ours, theirs map[string]any) map[string]any {
+	names := map[string]bool{}
+	for _, obj := range []map[string]any{base, ours, theirs} {
+		for name := range obj {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted) // so that conflicts are reported in a stable order
+
+	member := func(obj map[string]any, name string) any {
+		if v, ok := obj[name]; ok {
+			return v
+		}
+		return Missing{}
+	}
+	result := map[string]any{}
+	for _, name := range sorted {
+		v := m.merge(appendPointer(path, name), member(base, name), member(ours, name), member(theirs, name))
+		if _, ok := v.(Missing); !ok {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+// A change replaces the elements base[start:end] of an array with repl.
+type change struct {
+	start, end int
+	repl       []any
+}
+
+// changes returns the changes that transform base into other.
+func changes(base, other []any) []change {
+	diffs := lcs.DiffAnySlices(base, other, comparer{})
+	cs := make([]change, len(diffs))
+	for i, d := range diffs {
+		cs[i] = change{d.Start, d.End, other[d.ReplStart:d.ReplEnd]}
+	}
+	return cs
+}
+
+// overlaps reports whether two changes affect the same elements, or insert
+// elements at the same place or within the other's elements.
+func (c change) overlaps(d change) bool {
+	switch {
+	case c.start == c.end:
+		return d.start <= c.start && c.start <= d.end
+	case d.start == d.end:
+		return c.start <= d.start && d.start <= c.end
+	}
+	return c.start < d.end && d.start < c.end
+}
+
+// apply returns the elements base[start:end] with those of cs applied.
+func apply(base []any, start, end int, cs []change) []any {
+	var result []any
+	for _, c := range cs {
+		result = append(result, base[start:c.start]...)
+		result = append(result, c.repl...)
+		start = c.start
+		if c.end > start {
+			start = c.end
+		}
+	}
+	return append(result, base[start:end]...)
+}
+
+// array merges three arrays, in the manner of diff3.
+func (m *merger) array(path string, base, ours, theirs []any) []any {
+	oc, tc := changes(base, ours), changes(base, theirs)
+
+	var result []any
+	pos := 0 // the next element of base to copy
+	for len(oc) != 0 || len(tc) != 0 {
+		// Gather the overlapping changes that begin with the earliest.
+		var group change
+		if len(tc) == 0 || len(oc) != 0 && oc[0].start <= tc[0].start {
+			group = oc[0]
+		} else {
+			group = tc[0]
+		}
+		var o, t int // the numbers of our and their changes in the group
+		for {
+			if o < len(oc) && group.overlaps(oc[o]) {
+				group = group.union(oc[o])
+				o++
+			} else if t < len(tc) && group.overlaps(tc[t]) {
+				group = group.union(tc[t])
+				t++
+			} else {
+				break
+			}
+		}
+
+		result = append(result, base[pos:group.start]...)
+		pos = group.end
+		switch {
+		case t == 0:
+			result = append(result, apply(base, group.start, group.end, oc[:o])...)
+		case o == 0:
+			result = append(result, apply(base, group.start, group.end, tc[:t])...)
+		default:
+			b := base[group.start:group.end]
+			ov := apply(base, group.start, group.end, oc[:o])
+			tv := apply(base, group.start, group.end, tc[:t])
+			switch {
+			case equal(ov, tv):
+				result = append(result, ov...)
+			case len(b) == 1 && len(ov) == 1 && len(tv) == 1:
+				result = append(result, m.merge(appendPointer(path, strconv.Itoa(len(result))), b[0], ov[0], tv[0]))
+			default:
+				result = append(result, m.conflict(path, b, ov, tv).([]any)...)
+			}
+		}
+		oc, tc = oc[o:], tc[t:]
+	}
+	return append(result, base[pos:]...)
+}
+
+// union returns the smallest change that spans c and d. Its replacement is
+// not meaningful.
+func (c change) union(d change) change {
+	if d.start < c.start {
+		c.start = d.start
+	}
+	if d.end > c.end {
+		c.end = d.end
+	}
+	return c
+}
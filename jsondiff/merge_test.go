@@ -0,0 +1,139 @@
+package jsondiff_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/pgavlin/diff/jsondiff"
+)
+
+func TestMergeJSON(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		base, ours, theirs string
+		opts               *jsondiff.MergeOptions
+		want               string
+		conflicts          []string // the paths of the conflicts
+	}{
+		{
+			name: "independent members",
+			base: `{"a":1,"b":2,"c":{"d":3,"e":4}}`, ours: `{"a":10,"b":2,"c":{"d":3,"e":4}}`, theirs: `{"a":1,"c":{"d":3,"e":40},"f":5}`,
+			want: `{"a":10,"c":{"d":3,"e":40},"f":5}`,
+		},
+		{
+			name: "same change",
+			base: `{"a":1}`, ours: `{"a":2,"b":[1]}`, theirs: `{"a":2.0,"b":[1]}`,
+			want: `{"a":2,"b":[1]}`,
+		},
+		{
+			name: "conflicting changes",
+			base: `{"a":1,"b":1}`, ours: `{"a":2,"b":1}`, theirs: `{"a":3}`,
+			want:      `{"a":2}`,
+			conflicts: []string{"/a"},
+		},
+		{
+			name: "delete and modify",
+			base: `{"a":{"x":1}}`, ours: `{}`, theirs: `{"a":{"x":2}}`,
+			want:      `{}`,
+			conflicts: []string{"/a"},
+		},
+		{
+			name: "both add objects",
+			base: `{}`, ours: `{"a":{"x":1,"y":1}}`, theirs: `{"a":{"x":1,"z":1}}`,
+			want: `{"a":{"x":1,"y":1,"z":1}}`,
+		},
+		{
+			name: "array edits in different places",
+			base: `[1,2,3,4,5,6]`, ours: `[0,1,2,3,4,5,6]`, theirs: `[1,2,4,5,6,7]`,
+			want: `[0,1,2,4,5,6,7]`,
+		},
+		{
+			name: "array element merged recursively",
+			base: `[{"id":1,"a":1,"b":1},{"id":2}]`, ours: `[{"id":1,"a":2,"b":1},{"id":2}]`, theirs: `[{"id":1,"a":1,"b":2},{"id":2}]`,
+			want: `[{"a":2,"b":2,"id":1},{"id":2}]`,
+		},
+		{
+			name: "array element conflict",
+			base: `[{"a":1},1]`, ours: `[{"a":2},1]`, theirs: `[{"a":3},1]`,
+			want:      `[{"a":2},1]`,
+			conflicts: []string{"/0/a"},
+		},
+		{
+			name: "array run conflict",
+			base: `[1,2,3]`, ours: `[1,"x","y",3]`, theirs: `[1,"z",3]`,
+			want:      `[1,"x","y",3]`,
+			conflicts: []string{""},
+		},
+		{
+			name: "insertions at the same place",
+			base: `{"l":[1,2]}`, ours: `{"l":[1,"a",2]}`, theirs: `{"l":[1,"b",2]}`,
+			want:      `{"l":[1,"a",2]}`,
+			conflicts: []string{"/l"},
+		},
+		{
+			name: "prefer theirs",
+			base: `{"a":1,"b":{"c":1}}`, ours: `{"a":2,"b":{"c":2}}`, theirs: `{"a":3,"b":{"c":3}}`,
+			opts: &jsondiff.MergeOptions{Strategy: jsondiff.PreferTheirs},
+			want: `{"a":3,"b":{"c":3}}`,
+		},
+		{
+			name: "strategies by path",
+			base: `{"a":1,"b":{"c":1,"d":1},"bb":1}`, ours: `{"a":2,"b":{"c":2,"d":2},"bb":2}`, theirs: `{"a":3,"b":{"c":3,"d":3},"bb":3}`,
+			opts: &jsondiff.MergeOptions{
+				Strategy: jsondiff.PreferOurs,
+				Paths:    map[string]jsondiff.Strategy{"/b": jsondiff.PreferTheirs, "/b/d": jsondiff.Report},
+			},
+			want:      `{"a":2,"b":{"c":3,"d":2},"bb":2}`,
+			conflicts: []string{"/b/d"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, conflicts, err := jsondiff.MergeJSON([]byte(test.base), []byte(test.ours), []byte(test.theirs), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+			var paths []string
+			for _, c := range conflicts {
+				paths = append(paths, c.Path)
+			}
+			if !reflect.DeepEqual(paths, test.conflicts) {
+				t.Errorf("got conflicts at %q, want %q", paths, test.conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeConflictValues(t *testing.T) {
+	_, conflicts, err := jsondiff.MergeJSON([]byte(`{"a":1,"l":[1,2,3]}`), []byte(`{"l":[1,4,3]}`), []byte(`{"a":2,"l":[1,5,6,3]}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []jsondiff.Conflict{
+		{Path: "/a", Base: json.Number("1"), Ours: jsondiff.Missing{}, Theirs: json.Number("2")},
+		{Path: "/l", Base: []any{json.Number("2")}, Ours: []any{json.Number("4")}, Theirs: []any{json.Number("5"), json.Number("6")}},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("got %#v, want %#v", conflicts, want)
+	}
+}
+
+// TestMergeRandom checks that merging a change with no change, or with
+// itself, gives the change.
+func TestMergeRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		base := randomValue(rng, 4)
+		ours := mutate(rng, base, 4)
+		for _, args := range [][3]any{{base, ours, base}, {base, base, ours}, {base, ours, ours}} {
+			got, conflicts := jsondiff.MergeValues(args[0], args[1], args[2], nil)
+			if len(conflicts) != 0 || len(jsondiff.DiffValues(got, ours)) != 0 {
+				t.Fatalf("merge gave %v with conflicts %v, want %v", got, conflicts, ours)
+			}
+		}
+	}
+}
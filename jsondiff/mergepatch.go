@@ -0,0 +1,87 @@
+package jsondiff
+
+import "fmt"
+
+// MergePatch returns a JSON Merge Patch that transforms the JSON document a
+// into b.
+//
+// A merge patch cannot set a member to null, since null marks a member for
+// removal, and it replaces arrays as a whole. If b contains null members
+// that are new or changed, the returned patch removes them instead; use Diff
+// when that matters.
+func MergePatch(a, b []byte) ([]byte, error) {
+	av, err := decode(a)
+	if err != nil {
+		return nil, fmt.Errorf("old document: %w", err)
+	}
+	bv, err := decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("new document: %w", err)
+	}
+	return encode(MergePatchValues(av, bv))
+}
+
+// MergePatchValues returns a JSON Merge Patch, as a decoded JSON value, that
+// transforms the decoded JSON value a into b. See MergePatch.
+func MergePatchValues(a, b any) any {
+	ao, aok := a.(map[string]any)
+	bo, bok := b.(map[string]any)
+	if !aok || !bok {
+		return b
+	}
+	patch := map[string]any{}
+	for name := range ao {
+		if _, ok := bo[name]; !ok {
+			patch[name] = nil
+		}
+	}
+	for name, bv := range bo {
+		av, ok := ao[name]
+		switch {
+		case !ok:
+			patch[name] = bv
+		case !equal(av, bv):
+			patch[name] = MergePatchValues(av, bv)
+		}
+	}
+	return patch
+}
+
+// ApplyMergePatch applies the JSON Merge Patch patch to the JSON document
+// doc and returns the compactly encoded result.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("patch: %w", err)
+	}
+	return encode(ApplyMergePatchValue(v, p))
+}
+
+// ApplyMergePatchValue applies a JSON Merge Patch to a decoded JSON value
+// and returns the result, as described in section 2 of RFC 7386. The value
+// is not modified.
+func ApplyMergePatchValue(doc, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return clone(patch)
+	}
+	target, ok := doc.(map[string]any)
+	result := map[string]any{}
+	if ok {
+		for name, v := range target {
+			result[name] = clone(v)
+		}
+	}
+	for name, v := range p {
+		if v == nil {
+			delete(result, name)
+		} else {
+			result[name] = ApplyMergePatchValue(result[name], v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package jsondiff_test
+
+import (
+	"testing"
+
+	"github.com/pgavlin/diff/jsondiff"
+)
+
+// The examples of appendix A of RFC 7386.
+var mergePatchTests = []struct {
+	doc, patch, want string
+}{
+	{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+	{`{"a":"b"}`, `{"a":null}`, `{}`},
+	{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+	{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+	{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+	{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+	{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+	{`{"a":"b"}`, `["c"]`, `["c"]`},
+	{`{"a":"foo"}`, `null`, `null`},
+	{`{"a":"foo"}`, `"bar"`, `"bar"`},
+	{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+	{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+	{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	for _, test := range mergePatchTests {
+		got, err := jsondiff.ApplyMergePatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("ApplyMergePatch(%s, %s): %v", test.doc, test.patch, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("ApplyMergePatch(%s, %s) = %s, want %s", test.doc, test.patch, got, test.want)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	for _, test := range []struct {
+		a, b, want string
+	}{
+		{`{"a":1}`, `{"a":1}`, `{}`},
+		{`{"a":1,"b":{"c":1,"d":2}}`, `{"b":{"c":1,"d":3},"e":[1]}`, `{"a":null,"b":{"d":3},"e":[1]}`},
+		{`{"a":[1,2]}`, `{"a":[1,3]}`, `{"a":[1,3]}`},
+		{`{"a":1}`, `[1]`, `[1]`},
+		{`[1]`, `{"a":1}`, `{"a":1}`},
+	} {
+		got, err := jsondiff.MergePatch([]byte(test.a), []byte(test.b))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", test.a, test.b, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", test.a, test.b, got, test.want)
+		}
+		applied, err := jsondiff.ApplyMergePatch([]byte(test.a), got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, _ := jsondiff.Diff(applied, []byte(test.b)); len(p) != 0 {
+			t.Errorf("applying %s to %s gave %s, want %s", got, test.a, applied, test.b)
+		}
+	}
+}
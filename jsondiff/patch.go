@@ -0,0 +1,478 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/diff/lcs"
+)
+
+// An Operation is a single operation of a JSON Patch.
+type Operation struct {
+	// Op is one of "add", "remove", "replace", "move", "copy" or "test".
+	Op string
+	// Path is a JSON Pointer to the location the operation changes or
+	// tests.
+	Path string
+	// From is a JSON Pointer to the source of a move or copy.
+	From string
+	// Value is the value that an add, replace or test operation stores or
+	// expects.
+	Value any
+}
+
+// operation is the JSON encoding of an Operation.
+type operation struct {
+	Op    string          `json:"op"`
+	From  *string         `json:"from,omitempty"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// hasValue reports whether an operation of the given kind has a value.
+func hasValue(op string) bool {
+	return op == "add" || op == "replace" || op == "test"
+}
+
+// MarshalJSON encodes the operation as a JSON Patch operation object. The
+// "from" member is present only for moves and copies, and the "value"
+// member only for adds, replaces and tests.
+func (op Operation) MarshalJSON() ([]byte, error) {
+	o := operation{Op: op.Op, Path: op.Path}
+	if op.Op == "move" || op.Op == "copy" {
+		o.From = &op.From
+	}
+	if hasValue(op.Op) {
+		v, err := encode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		o.Value = v
+	}
+	return encode(o)
+}
+
+// UnmarshalJSON decodes a JSON Patch operation object. Numbers in the value
+// are decoded as json.Number.
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	var o operation
+	if err := json.Unmarshal(data, &o); err != nil {
+		return err
+	}
+	*op = Operation{Op: o.Op, Path: o.Path}
+	switch {
+	case o.Op == "move" || o.Op == "copy":
+		if o.From == nil {
+			return fmt.Errorf("%s operation is missing \"from\"", o.Op)
+		}
+		op.From = *o.From
+	case hasValue(o.Op):
+		if o.Value == nil {
+			return fmt.Errorf("%s operation is missing \"value\"", o.Op)
+		}
+		v, err := decode(o.Value)
+		if err != nil {
+			return err
+		}
+		op.Value = v
+	case o.Op != "remove":
+		return fmt.Errorf("unknown operation %q", o.Op)
+	}
+	return nil
+}
+
+// A Patch is a JSON Patch: a sequence of operations that are applied in
+// order. Its JSON encoding is an array of operation objects.
+type Patch []Operation
+
+// ParsePatch decodes a JSON Patch document.
+func ParsePatch(data []byte) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// String returns the JSON encoding of the patch.
+func (p Patch) String() string {
+	b, err := encode(p)
+	if err != nil {
+		return fmt.Sprintf("<invalid patch: %v>", err)
+	}
+	return string(b)
+}
+
+// Diff returns a JSON Patch that transforms the JSON document a into b.
+func Diff(a, b []byte) (Patch, error) {
+	av, err := decode(a)
+	if err != nil {
+		return nil, fmt.Errorf("old document: %w", err)
+	}
+	bv, err := decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("new document: %w", err)
+	}
+	return DiffValues(av, bv), nil
+}
+
+// DiffValues returns a JSON Patch that transforms the decoded JSON value a
+// into b.
+//
+// Objects are compared member by member, and a member that is removed while
+// another with an equal value is added becomes a move. Arrays are aligned by
+// their longest common subsequence of equal elements; elements that are
+// removed from one place and inserted at another become moves, and other
+// changed elements are diffed recursively. Values of different types, and
+// unequal scalars, are replaced. The patch is empty if a and b are equal.
+func DiffValues(a, b any) Patch {
+	var d differ
+	d.diff("", a, b)
+	return d.ops
+}
+
+// A differ accumulates the operations of a patch.
+type differ struct {
+	ops Patch
+}
+
+func (d *differ) add(op, from, path string, value any) {
+	d.ops = append(d.ops, Operation{Op: op, From: from, Path: path, Value: value})
+}
+
+// diff appends the operations that transform the value a at path into b.
+func (d *differ) diff(path string, a, b any) {
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			d.object(path, a, b)
+			return
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			d.array(path, a, b)
+			return
+		}
+	}
+	if !equal(a, b) {
+		d.add("replace", "", path, b)
+	}
+}
+
+// object diffs two objects. Members are visited in order of their names.
+func (d *differ) object(path string, a, b map[string]any) {
+	names := make([]string, 0, len(a)+len(b))
+	var removed []string
+	for name := range a {
+		names = append(names, name)
+		if _, ok := b[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(removed)
+
+	// Pair added members with removed members of equal value.
+	renamed := map[string]string{} // from the new name to the old name
+	for _, name := range names {
+		if _, ok := a[name]; ok {
+			continue
+		}
+		for i, old := range removed {
+			if equal(a[old], b[name]) {
+				renamed[name] = old
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+	}
+	moved := map[string]bool{}
+	for _, old := range renamed {
+		moved[old] = true
+	}
+
+	for _, name := range names {
+		av, inA := a[name]
+		bv, inB := b[name]
+		p := appendPointer(path, name)
+		switch {
+		case inA && inB:
+			d.diff(p, av, bv)
+		case inA:
+			if !moved[name] {
+				d.add("remove", "", p, nil)
+			}
+		default:
+			if old, ok := renamed[name]; ok {
+				d.add("move", appendPointer(path, old), p, nil)
+			} else {
+				d.add("add", "", p, bv)
+			}
+		}
+	}
+}
+
+// array diffs two arrays.
+func (d *differ) array(path string, a, b []any) {
+	diffs := lcs.DiffAnySlices(a, b, comparer{})
+
+	// source[j] is the index of the element of a that becomes b[j], or -1
+	// if b[j] is inserted. An element that is in the common subsequence is
+	// kept; other elements of b come from a only if they are moved or
+	// replaced.
+	source := make([]int, len(b))
+	for j := range source {
+		source[j] = -1
+	}
+	used := make([]bool, len(a))
+	keep := func(ai, aj, bi int) {
+		for ; ai < aj; ai, bi = ai+1, bi+1 {
+			source[bi], used[ai] = ai, true
+		}
+	}
+	ai, bi := 0, 0
+	for _, df := range diffs {
+		keep(ai, df.Start, bi)
+		ai, bi = df.End, df.ReplEnd
+	}
+	keep(ai, len(a), bi)
+
+	// Pair inserted elements with equal deleted elements as moves.
+	moved := make([]bool, len(a))
+	for _, dj := range diffs {
+		for j := dj.ReplStart; j < dj.ReplEnd; j++ {
+		search:
+			for _, di := range diffs {
+				for i := di.Start; i < di.End; i++ {
+					if !used[i] && equal(a[i], b[j]) {
+						source[j], used[i], moved[i] = i, true, true
+						break search
+					}
+				}
+			}
+		}
+	}
+
+	// Within each changed region, pair the remaining deleted and inserted
+	// elements in order as replacements.
+	replaced := make([]bool, len(b))
+	for _, df := range diffs {
+		i, j := df.Start, df.ReplStart
+		for {
+			for i < df.End && used[i] {
+				i++
+			}
+			for j < df.ReplEnd && source[j] >= 0 {
+				j++
+			}
+			if i == df.End || j == df.ReplEnd {
+				break
+			}
+			source[j], used[i], replaced[j] = i, true, true
+		}
+	}
+
+	// Remove the unused elements, last first so that the indices of the
+	// others are unaffected.
+	cur := make([]int, 0, len(a)) // the indices in a of the current elements, or -1 for inserted elements
+	for i := len(a) - 1; i >= 0; i-- {
+		if !used[i] {
+			d.add("remove", "", appendPointer(path, strconv.Itoa(i)), nil)
+		}
+	}
+	for i := range a {
+		if used[i] {
+			cur = append(cur, i)
+		}
+	}
+
+	// Build b from front to back. The elements of b before index j are
+	// cur[:next], except that any elements that are yet to be moved later
+	// are interleaved with them.
+	next := 0
+	for j := range b {
+		i := source[j]
+		if i < 0 {
+			d.add("add", "", appendPointer(path, strconv.Itoa(next)), b[j])
+			cur = insert(cur, next, -1)
+			next++
+			continue
+		}
+
+		p := 0
+		for cur[p] != i {
+			p++
+		}
+		inPlace := p >= next
+		for k := next; k < p && inPlace; k++ {
+			inPlace = moved[cur[k]]
+		}
+		if inPlace {
+			next = p + 1
+		} else {
+			if p < next {
+				next--
+			}
+			d.add("move", appendPointer(path, strconv.Itoa(p)), appendPointer(path, strconv.Itoa(next)), nil)
+			cur = insert(append(cur[:p], cur[p+1:]...), next, i)
+			p = next
+			next++
+		}
+		if replaced[j] {
+			d.diff(appendPointer(path, strconv.Itoa(p)), a[i], b[j])
+		}
+	}
+}
+
+func insert(s []int, i, v int) []int {
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+// Apply applies the patch to the JSON document doc and returns the compactly
+// encoded result. The patch is applied atomically: if any operation fails,
+// Apply returns an error that identifies it.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = p.ApplyValue(v); err != nil {
+		return nil, err
+	}
+	return encode(v)
+}
+
+// ApplyValue applies the patch to a decoded JSON value and returns the
+// result. The value is not modified.
+func (p Patch) ApplyValue(doc any) (any, error) {
+	doc = clone(doc)
+	for i, op := range p {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// apply applies a single operation to doc, which it may modify.
+func (op Operation) apply(doc any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add":
+		return add(doc, path, clone(op.Value))
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return clone(op.Value), nil
+		}
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, clone(op.Value))
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		if op.Op == "copy" {
+			if v, err = get(doc, from); err != nil {
+				return nil, err
+			}
+			return add(doc, path, clone(v))
+		}
+		if op.From == op.Path {
+			_, err := get(doc, from)
+			return doc, err
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into itself", op.From)
+		}
+		if doc, v, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "test":
+		v, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(v, op.Value) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// add adds v at path in doc, inserting it if path names an array element.
+func add(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return update(doc, path, func(c any, token string) (any, error) {
+		switch c := c.(type) {
+		case map[string]any:
+			c[token] = v
+			return c, nil
+		case []any:
+			i := len(c)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(c)+1); err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = v
+			return c, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to %s", token, kind(c))
+	})
+}
+
+// remove removes the value at path from doc and returns it.
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	var removed any
+	doc, err := update(doc, path, func(c any, token string) (any, error) {
+		v, err := child(c, token)
+		if err != nil {
+			return nil, err
+		}
+		removed = v
+		switch c := c.(type) {
+		case map[string]any:
+			delete(c, token)
+			return c, nil
+		case []any:
+			i, _ := arrayIndex(token, len(c))
+			return append(c[:i], c[i+1:]...), nil
+		}
+		panic("unreachable")
+	})
+	return doc, removed, err
+}
@@ -0,0 +1,251 @@
+package jsondiff_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/jsondiff"
+)
+
+// The examples of appendix A of RFC 6902.
+var applyTests = []struct {
+	doc, patch, want string
+	err              string
+}{
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, ""},
+	{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, ""},
+	{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, ""},
+	{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, ""},
+	{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, ""},
+	{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, ""},
+	{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, ""},
+	{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, ""},
+	{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", "operation 0 (test /baz): test failed"},
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`, ""},
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", `operation 0 (add /baz/bat): member "baz" not found`},
+	{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, ""},
+	{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, "", "operation 0 (test /~01): test failed"},
+	{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`, ""},
+
+	// Other cases.
+	{`{"a":[1,2]}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"add","path":"/b/0","value":0}]`, `{"a":[1,2],"b":[0,1,2]}`, ""},
+	{`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, "", "operation 0 (move /a/c): cannot move /a into itself"},
+	{`{"a":1}`, `[{"op":"replace","path":"","value":[1e2]}]`, `[1e2]`, ""},
+	{`{"a":1.0}`, `[{"op":"test","path":"/a","value":1}]`, `{"a":1.0}`, ""},
+	{`[1,2]`, `[{"op":"remove","path":"/2"}]`, "", "operation 0 (remove /2): array index 2 out of range [0, 2)"},
+	{`[1,2]`, `[{"op":"add","path":"/01","value":0}]`, "", `operation 0 (add /01): invalid array index "01"`},
+	{`{"a":"<&>"}`, `[]`, `{"a":"<&>"}`, ""},
+}
+
+func TestApply(t *testing.T) {
+	for _, test := range applyTests {
+		p, err := jsondiff.ParsePatch([]byte(test.patch))
+		if err != nil {
+			t.Errorf("ParsePatch(%s): %v", test.patch, err)
+			continue
+		}
+		got, err := p.Apply([]byte(test.doc))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Apply(%s, %s): error %v, want %q", test.doc, test.patch, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Apply(%s, %s): %v", test.doc, test.patch, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("Apply(%s, %s) = %s, want %s", test.doc, test.patch, got, test.want)
+		}
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	for _, test := range []struct {
+		patch, err string
+	}{
+		{`[{"op":"add","path":"/a"}]`, `add operation is missing "value"`},
+		{`[{"op":"move","path":"/a"}]`, `move operation is missing "from"`},
+		{`[{"op":"frob","path":"/a"}]`, `unknown operation "frob"`},
+	} {
+		if _, err := jsondiff.ParsePatch([]byte(test.patch)); err == nil || err.Error() != test.err {
+			t.Errorf("ParsePatch(%s): error %v, want %q", test.patch, err, test.err)
+		}
+	}
+
+	// A null value is present, and survives a round trip.
+	const null = `[{"op":"add","path":"/a","value":null}]`
+	p, err := jsondiff.ParsePatch([]byte(null))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.String(); got != null {
+		t.Errorf("got %s, want %s", got, null)
+	}
+}
+
+var diffTests = []struct {
+	a, b string
+	want string
+}{
+	{`{"a":1}`, `{"a":1}`, `null`},
+	{`{"a":1,"b":2}`, `{"b":2,"a":1}`, `null`},
+	{`[1, 2.0]`, `[1.0, 2]`, `null`},
+	{`{"a":1}`, `{"a":2}`, `[{"op":"replace","path":"/a","value":2}]`},
+	{`{"a":1,"b":{"c":[1]}}`, `{"b":{"c":[1,2]},"d":null}`, `[{"op":"remove","path":"/a"},{"op":"add","path":"/b/c/1","value":2},{"op":"add","path":"/d","value":null}]`},
+	{`{"old":{"x":1}}`, `{"new":{"x":1}}`, `[{"op":"move","from":"/old","path":"/new"}]`},
+	{`{"a/b":1,"m~n":2}`, `{"a/b":3}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`},
+	{`[1,2,3,4,5]`, `[1,3,5]`, `[{"op":"remove","path":"/3"},{"op":"remove","path":"/1"}]`},
+	{`[1,2,3]`, `[0,1,2,3,4]`, `[{"op":"add","path":"/0","value":0},{"op":"add","path":"/4","value":4}]`},
+	{`["m","a","b","c"]`, `["a","b","c","m"]`, `[{"op":"move","from":"/0","path":"/3"}]`},
+	{`["a","b","c","m"]`, `["m","a","b","c"]`, `[{"op":"move","from":"/3","path":"/0"}]`},
+	{`[{"id":1,"v":"x"},{"id":2,"v":"y"}]`, `[{"id":1,"v":"x"},{"id":2,"v":"z"}]`, `[{"op":"replace","path":"/1/v","value":"z"}]`},
+	{`[1,{"a":1}]`, `[{"a":2},1]`, `[{"op":"remove","path":"/1"},{"op":"add","path":"/0","value":{"a":2}}]`},
+	{`{"a":[1]}`, `{"a":{"0":1}}`, `[{"op":"replace","path":"/a","value":{"0":1}}]`},
+	{`1`, `"1"`, `[{"op":"replace","path":"","value":"1"}]`},
+	{`12345678901234567890`, `12345678901234567891`, `[{"op":"replace","path":"","value":12345678901234567891}]`},
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range diffTests {
+		p, err := jsondiff.Diff([]byte(test.a), []byte(test.b))
+		if err != nil {
+			t.Errorf("Diff(%s, %s): %v", test.a, test.b, err)
+			continue
+		}
+		if got := p.String(); got != test.want {
+			t.Errorf("Diff(%s, %s) = %s, want %s", test.a, test.b, got, test.want)
+		}
+		checkPatch(t, test.a, test.b, p)
+	}
+
+	if _, err := jsondiff.Diff([]byte(`{}`), []byte(`{} x`)); err == nil {
+		t.Error("Diff accepted trailing garbage")
+	}
+}
+
+// checkPatch checks that p transforms a into b.
+func checkPatch(t *testing.T, a, b string, p jsondiff.Patch) {
+	t.Helper()
+	got, err := p.Apply([]byte(a))
+	if err != nil {
+		t.Errorf("applying %s to %s: %v", p, a, err)
+		return
+	}
+	if _, err := jsondiff.ParsePatch([]byte(p.String())); err != nil {
+		t.Errorf("reparsing %s: %v", p, err)
+	}
+	again, err := jsondiff.Diff(got, []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Errorf("applying %s to %s gave %s, want %s", p, a, got, b)
+	}
+}
+
+// randomValue returns a random JSON document that is likely to share
+// structure with others generated from similar seeds.
+func randomValue(rng *rand.Rand, depth int) any {
+	n := rng.Intn(8)
+	if depth == 0 {
+		n = rng.Intn(4)
+	}
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return rng.Intn(2) == 0
+	case 2:
+		return json.Number(fmt.Sprint(rng.Intn(5)))
+	case 3:
+		return string(rune('a' + rng.Intn(4)))
+	case 4, 5:
+		a := make([]any, rng.Intn(6))
+		for i := range a {
+			a[i] = randomValue(rng, depth-1)
+		}
+		return a
+	default:
+		o := map[string]any{}
+		for i := rng.Intn(5); i > 0; i-- {
+			o[string(rune('k'+rng.Intn(5)))] = randomValue(rng, depth-1)
+		}
+		return o
+	}
+}
+
+// mutate returns a copy of v with random changes.
+func mutate(rng *rand.Rand, v any, depth int) any {
+	if rng.Intn(8) == 0 {
+		return randomValue(rng, depth)
+	}
+	switch v := v.(type) {
+	case []any:
+		var a []any
+		for _, e := range v {
+			switch rng.Intn(6) {
+			case 0: // drop
+			case 1:
+				a = append(a, randomValue(rng, depth-1), e)
+			default:
+				a = append(a, mutate(rng, e, depth-1))
+			}
+		}
+		if len(a) > 1 && rng.Intn(3) == 0 {
+			i, j := rng.Intn(len(a)), rng.Intn(len(a))
+			a[i], a[j] = a[j], a[i]
+		}
+		return a
+	case map[string]any:
+		o := map[string]any{}
+		for k, e := range v {
+			switch rng.Intn(6) {
+			case 0: // drop
+			case 1:
+				o[k+"'"] = e
+			default:
+				o[k] = mutate(rng, e, depth-1)
+			}
+		}
+		return o
+	}
+	return v
+}
+
+func TestDiffRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a := randomValue(rng, 4)
+		b := mutate(rng, a, 4)
+		p := jsondiff.DiffValues(a, b)
+		got, err := p.ApplyValue(a)
+		if err != nil {
+			t.Fatalf("applying %s: %v", p, err)
+		}
+		if again := jsondiff.DiffValues(got, b); len(again) != 0 {
+			as, _ := json.Marshal(a)
+			bs, _ := json.Marshal(b)
+			t.Fatalf("DiffValues(%s, %s) = %s, which leaves %s", as, bs, p, again)
+		}
+	}
+}
+
+func Example() {
+	before := `{"name": "diff", "tags": ["go", "text"], "version": 1}`
+	after := `{"name": "diff", "tags": ["go", "json", "text"], "version": 2}`
+
+	p, err := jsondiff.Diff([]byte(before), []byte(after))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(strings.ReplaceAll(p.String(), "},", "},\n"))
+
+	// Output:
+	// [{"op":"add","path":"/tags/1","value":"json"},
+	// {"op":"replace","path":"/version","value":2}]
+}
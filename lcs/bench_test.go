@@ -0,0 +1,119 @@
+package lcs
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+// BenchmarkDiffLines measures line diffs at the default limit and with a
+// search deep enough to find a true LCS.
+func BenchmarkDiffLines(b *testing.B) {
+	type input struct {
+		name string
+		a, b []string
+	}
+	var inputs []input
+	for _, name := range []string{"journal-register", "glagolitic"} {
+		base, err := os.ReadFile("../testdata/" + name + "-base.txt")
+		if err != nil {
+			b.Fatalf("reading test data: %v", err)
+		}
+		edit, err := os.ReadFile("../testdata/" + name + "-edit.txt")
+		if err != nil {
+			b.Fatalf("reading test data: %v", err)
+		}
+		inputs = append(inputs, input{name, strings.SplitAfter(string(base), "\n"), strings.SplitAfter(string(edit), "\n")})
+	}
+
+	// Long lines that differ only at their ends and repeat often are costly
+	// to compare.
+	rng := rand.New(rand.NewSource(1))
+	long := input{name: "long-lines"}
+	pad := strings.Repeat("x", 200)
+	for i := 0; i < 2000; i++ {
+		long.a = append(long.a, fmt.Sprintf("%s%03d\n", pad, rng.Intn(50)))
+		long.b = append(long.b, fmt.Sprintf("%s%03d\n", pad, rng.Intn(50)))
+	}
+	inputs = append(inputs, long)
+
+	for _, in := range inputs {
+		for _, limit := range []int{0, 1000} {
+			bounds := Bounds{Limit: limit}
+			b.Run(fmt.Sprintf("%s/limit=%d", in.name, limit), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					DiffLinesBounded(in.a, in.b, bounds)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkDiffLinesMinimal compares the linear-space search for a true
+// LCS with an unbounded two-sided search, whose labels grow with the
+// square of the number of edits.
+func BenchmarkDiffLinesMinimal(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	for _, edits := range []int{10, 1000} {
+		var x, y []string
+		for i := 0; i < 10000; i++ {
+			line := fmt.Sprintf("line %d\n", i)
+			x = append(x, line)
+			if rng.Intn(10000) < edits {
+				line = fmt.Sprintf("edit %d\n", i)
+			}
+			y = append(y, line)
+		}
+		b.Run(fmt.Sprintf("edits=%d", edits), func(b *testing.B) {
+			b.Run("minimal", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					DiffLinesMinimal(x, y)
+				}
+			})
+			b.Run("unbounded", func(b *testing.B) {
+				b.ReportAllocs()
+				bounds := Bounds{Limit: len(x) + len(y)}
+				for i := 0; i < b.N; i++ {
+					DiffLinesBounded(x, y, bounds)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkDiffTextShort compares the bit-parallel search with the
+// two-sided search on pairs of short lines, as in a diff within lines.
+func BenchmarkDiffTextShort(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{40, 120, 250, 500} {
+		var pairs [][2]string
+		for i := 0; i < 100; i++ {
+			a := []byte(randstr("abcdefghij ", n))
+			c := append([]byte(nil), a...)
+			for k := 0; k < n/10; k++ {
+				c[rng.Intn(len(c))] = "xyz"[rng.Intn(3)]
+			}
+			pairs = append(pairs, [2]string{string(a), string(c)})
+		}
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.Run("bitparallel", func(b *testing.B) {
+				var s bitSearch
+				for i := 0; i < b.N; i++ {
+					p := pairs[i%len(pairs)]
+					s.text(p[0], p[1])
+				}
+			})
+			b.Run("twosided", func(b *testing.B) {
+				var s Scratch
+				for i := 0; i < b.N; i++ {
+					p := pairs[i%len(pairs)]
+					s.strings = stringSeqs{p[0], p[1]}
+					s.diff(&s.strings)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package lcs
+
+import "math/bits"
+
+// This file defines a bit-parallel LCS algorithm for short sequences, after
+// Hyyrö, "Bit-Parallel LCS-length Computation Revisited" (2004), which
+// refines Allison and Dix (1986). Row i of the dynamic programming table
+// L[i][j], the length of the LCS of A[:i] and B[:j], is kept as a bit
+// vector V of len(B) bits, whose zero bits are where L grows along the
+// row, so L[i][j] is the number of zeros among the first j bits of V. If
+// M is the vector of positions in B of A[i], the next row is
+//
+//	V' = (V + (V & M)) | (V &^ M)
+//
+// which computes 64 entries of the row for each word of the vector. The
+// search keeps every row, and reads an LCS from them backwards, so it
+// takes time and memory proportional to len(A)·len(B)/64, and is used
+// only when that is small.
+
+// maxBitWords bounds the memory of a bit-parallel search: the number of
+// words in the vectors of all of its rows and of the masks of its symbols.
+// It admits sequences of about 350 elements, short of those of 500 for
+// which the bounded two-sided search is faster, if not always exact
+// (BenchmarkDiffTextShort).
+const maxBitWords = 1 << 12
+
+// bitFits reports whether sequences of lengths n and m, the second of which
+// has at most nsyms distinct symbols, are small enough for a bit-parallel
+// search.
+func bitFits(n, m, nsyms int) bool {
+	return (n+1+nsyms+1)*((m+63)/64) <= maxBitWords
+}
+
+// A bitSearch holds the memory of a bit-parallel search. The zero
+// bitSearch is ready to use, and may be reused.
+type bitSearch struct {
+	small        [256]int32     // the symbols of bytes and runes below 256, plus one
+	large        map[rune]int32 // the symbols of other runes, plus one
+	asyms, bsyms []int32        // the symbols of A and B; -1 in A if not in B
+	masks        []uint64       // masks[s*w:][:w] has bit j set if B[j] has symbol s
+	rows         []uint64       // rows[i*w:][:w] is V for the first i elements of A
+	lcs          lcs
+}
+
+// text returns an lcs of a and b, or false if they are too large for a
+// bit-parallel search once their common prefix and suffix are removed.
+// The lcs is only valid until s is used again.
+func (s *bitSearch) text(a, b string) (lcs, bool) {
+	p := commonPrefixLenString(a, b)
+	q := commonSuffixLenString(a[p:], b[p:])
+	ma, mb := a[p:len(a)-q], b[p:len(b)-q]
+	if len(ma) == 0 || len(mb) == 0 {
+		return s.affixes(p, len(a)-q, len(b)-q, q), true
+	}
+	nsyms := len(mb)
+	if nsyms > len(s.small) {
+		nsyms = len(s.small)
+	}
+	if !bitFits(len(ma), len(mb), nsyms) {
+		return nil, false
+	}
+
+	nsyms = 0
+	s.bsyms = s.bsyms[:0]
+	for i := 0; i < len(mb); i++ {
+		c := mb[i]
+		if s.small[c] == 0 {
+			nsyms++
+			s.small[c] = int32(nsyms)
+		}
+		s.bsyms = append(s.bsyms, s.small[c]-1)
+	}
+	s.asyms = s.asyms[:0]
+	for i := 0; i < len(ma); i++ {
+		s.asyms = append(s.asyms, s.small[ma[i]]-1)
+	}
+	for i := 0; i < len(mb); i++ {
+		s.small[mb[i]] = 0
+	}
+	return s.search(nsyms, p, len(a)-q, len(b)-q, q), true
+}
+
+// runes is text for sequences of runes.
+func (s *bitSearch) runes(a, b []rune) (lcs, bool) {
+	p := commonPrefixLenRunes(a, b)
+	q := commonSuffixLenRunes(a[p:], b[p:])
+	ma, mb := a[p:len(a)-q], b[p:len(b)-q]
+	if len(ma) == 0 || len(mb) == 0 {
+		return s.affixes(p, len(a)-q, len(b)-q, q), true
+	}
+	if !bitFits(len(ma), len(mb), len(mb)) {
+		return nil, false
+	}
+
+	nsyms := 0
+	s.bsyms = s.bsyms[:0]
+	for _, r := range mb {
+		sym := s.symbol(r)
+		if sym == 0 {
+			nsyms++
+			sym = int32(nsyms)
+			if 0 <= r && r < 256 {
+				s.small[r] = sym
+			} else {
+				if s.large == nil {
+					s.large = make(map[rune]int32)
+				}
+				s.large[r] = sym
+			}
+		}
+		s.bsyms = append(s.bsyms, sym-1)
+	}
+	s.asyms = s.asyms[:0]
+	for _, r := range ma {
+		s.asyms = append(s.asyms, s.symbol(r)-1)
+	}
+	for _, r := range mb {
+		if 0 <= r && r < 256 {
+			s.small[r] = 0
+		} else {
+			delete(s.large, r)
+		}
+	}
+	return s.search(nsyms, p, len(a)-q, len(b)-q, q), true
+}
+
+// affixes returns the lcs of A and B when all that they have in common is
+// a prefix of length p and a suffix of length q from A[aj] and B[bj].
+func (s *bitSearch) affixes(p, aj, bj, q int) lcs {
+	s.lcs = s.lcs[:0]
+	if p > 0 {
+		s.lcs = append(s.lcs, diag{0, 0, p})
+	}
+	if q > 0 {
+		s.lcs = append(s.lcs, diag{aj, bj, q})
+	}
+	return s.lcs
+}
+
+// symbol returns the symbol of r plus one, or zero if r has none.
+func (s *bitSearch) symbol(r rune) int32 {
+	if 0 <= r && r < 256 {
+		return s.small[r]
+	}
+	return s.large[r]
+}
+
+// search computes an lcs of A and B, which have a common prefix of length
+// p and a common suffix of length q from A[aj] and B[bj], and whose
+// elements between them have the symbols s.asyms and s.bsyms.
+func (s *bitSearch) search(nsyms, p, aj, bj, q int) lcs {
+	n, m := len(s.asyms), len(s.bsyms)
+	w := (m + 63) / 64
+
+	// The last mask, of no positions, is that of the elements of A that
+	// are not in B.
+	s.masks = grow(s.masks, (nsyms+1)*w)
+	for i := range s.masks {
+		s.masks[i] = 0
+	}
+	for j, sym := range s.bsyms {
+		s.masks[int(sym)*w+j/64] |= 1 << (j % 64)
+	}
+
+	s.rows = grow(s.rows, (n+1)*w)
+	for k := range s.rows[:w] {
+		s.rows[k] = ^uint64(0)
+	}
+	for i, sym := range s.asyms {
+		if sym < 0 {
+			sym = int32(nsyms)
+		}
+		prev := s.rows[i*w : (i+1)*w]
+		row := s.rows[(i+1)*w : (i+2)*w][:len(prev)]
+		mask := s.masks[int(sym)*w : (int(sym)+1)*w][:len(prev)]
+		var carry uint64
+		for k, v := range prev {
+			u := v & mask[k]
+			var sum uint64
+			sum, carry = bits.Add64(v, u, carry)
+			row[k] = sum | v&^u
+		}
+	}
+
+	s.lcs = s.lcs[:0]
+	if q > 0 {
+		s.lcs = append(s.lcs, diag{aj, bj, q})
+	}
+	i, j := n, m
+	l := s.length(i, j)
+	for l > 0 {
+		if s.asyms[i-1] == s.bsyms[j-1] {
+			// A match always extends an LCS of the prefixes before it.
+			x, y := p+i-1, p+j-1
+			if last := len(s.lcs) - 1; last >= 0 && s.lcs[last].X == x+1 && s.lcs[last].Y == y+1 {
+				s.lcs[last] = diag{x, y, s.lcs[last].Len + 1}
+			} else {
+				s.lcs = append(s.lcs, diag{x, y, 1})
+			}
+			i, j, l = i-1, j-1, l-1
+		} else if s.length(i-1, j) == l {
+			i--
+		} else {
+			j--
+		}
+	}
+	if p > 0 {
+		s.lcs = append(s.lcs, diag{0, 0, p})
+	}
+	s.lcs.reverse()
+	return s.lcs
+}
+
+// length returns L[i][j], the length of an LCS of the first i and j
+// elements of A and B between their common prefix and suffix.
+func (s *bitSearch) length(i, j int) int {
+	w := (len(s.bsyms) + 63) / 64
+	row := s.rows[i*w : (i+1)*w]
+	l := 0
+	for _, v := range row[:j/64] {
+		l += bits.OnesCount64(^v)
+	}
+	if r := j % 64; r > 0 {
+		l += bits.OnesCount64(^row[j/64] & (1<<r - 1))
+	}
+	return l
+}
+
+// grow returns a slice of length n, reusing the memory of buf if it can.
+func grow(buf []uint64, n int) []uint64 {
+	if cap(buf) < n {
+		return make([]uint64, n)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,108 @@
+package lcs
+
+import (
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBitSearch(t *testing.T) {
+	rand.Seed(5)
+	var s bitSearch
+	for i := 0; i < 2000; i++ {
+		// Lengths past 64 span several words of the vectors, so that
+		// carries cross words.
+		n := rand.Intn(200)
+		a, b := randstr("abω\n", n), randstr("abωc\n", rand.Intn(200))
+
+		lcs, ok := s.text(a, b)
+		if !ok {
+			t.Fatalf("%q, %q: too large", a, b)
+		}
+		if !lcs.valid() {
+			t.Fatalf("%q, %q: invalid lcs %v", a, b, lcs)
+		}
+		if got, want := lcslen(lcs), lcsBytesLen(a, b); got != want {
+			t.Fatalf("%q, %q: got lcs of length %d, want %d", a, b, got, want)
+		}
+		checkDiffs(t, a, lcs.toDiffs(nil, len(a), len(b)), b)
+
+		ra, rb := []rune(a), []rune(b)
+		lcs, ok = s.runes(ra, rb)
+		if !ok {
+			t.Fatalf("%q, %q: too large", a, b)
+		}
+		if !lcs.valid() {
+			t.Fatalf("%q, %q: invalid rune lcs %v", a, b, lcs)
+		}
+		if got, want := lcslen(lcs), dynamicLCSLen(ra, rb); got != want {
+			t.Fatalf("%q, %q: got rune lcs of length %d, want %d", a, b, got, want)
+		}
+	}
+}
+
+// lcsBytesLen returns the length of a longest common subsequence of the
+// bytes of a and b.
+func lcsBytesLen(a, b string) int {
+	ra, rb := make([]rune, len(a)), make([]rune, len(b))
+	for i := range ra {
+		ra[i] = rune(a[i])
+	}
+	for i := range rb {
+		rb[i] = rune(b[i])
+	}
+	return dynamicLCSLen(ra, rb)
+}
+
+func TestBitSearchTooLarge(t *testing.T) {
+	var s bitSearch
+	a := strings.Repeat("a", 500) + strings.Repeat("b", 500)
+	b := strings.Repeat("b", 500) + strings.Repeat("a", 500)
+	if _, ok := s.text(a, b); ok {
+		t.Errorf("bit-parallel search of %d and %d bytes", len(a), len(b))
+	}
+	// Long texts with short differences fit once their common prefix and
+	// suffix are removed.
+	a, b = a+a, a+"x"+a
+	if _, ok := s.text(a, b); !ok {
+		t.Errorf("no bit-parallel search of texts that differ by one byte")
+	}
+}
+
+// TestBitSearchMemory checks that long sequences, whose masks would be as
+// large as their rows, are not given to the bit-parallel search.
+func TestBitSearchMemory(t *testing.T) {
+	distinct := make([]rune, 120000)
+	for i := range distinct {
+		distinct[i] = rune(0x10000 + i)
+	}
+	long := randstr("abcdefghij ", 250000)
+	for _, test := range []struct {
+		name string
+		f    func() []Diff
+	}{
+		{"insert runes", func() []Diff { return DiffRunes(nil, distinct) }},
+		{"delete runes", func() []Diff { return DiffRunes(distinct, nil) }},
+		{"replace runes", func() []Diff { return DiffRunes([]rune{'x'}, distinct) }},
+		{"insert text", func() []Diff { return DiffText("", long) }},
+		{"replace text", func() []Diff { return DiffText("x", long) }},
+	} {
+		var diffs []Diff
+		if n := allocated(func() { diffs = test.f() }); n > 1<<20 {
+			t.Errorf("%s: allocated %d bytes", test.name, n)
+		}
+		if len(diffs) != 1 {
+			t.Errorf("%s: got diffs %v", test.name, diffs)
+		}
+	}
+}
+
+// allocated returns the number of bytes that f allocates.
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
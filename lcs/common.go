@@ -19,17 +19,33 @@ type diag struct {
 	Len  int
 }
 
-// sort sorts in place, by lowest X, and if tied, inversely by Len
+// sort sorts in place, by lowest X, and if tied, inversely by Len.
+// An lcs has at most a few diagonals per edit, and is sorted but for being
+// the concatenation of two sorted parts, so an insertion sort is cheap and
+// does not allocate.
 func (l lcs) sort() lcs {
-	sort.Slice(l, func(i, j int) bool {
-		if l[i].X != l[j].X {
-			return l[i].X < l[j].X
+	for i := 1; i < len(l); i++ {
+		for j := i; j > 0 && l[j].less(l[j-1]); j-- {
+			l[j], l[j-1] = l[j-1], l[j]
 		}
-		return l[i].Len > l[j].Len
-	})
+	}
 	return l
 }
 
+func (d diag) less(e diag) bool {
+	if d.X != e.X {
+		return d.X < e.X
+	}
+	return d.Len > e.Len
+}
+
+// reverse reverses l in place.
+func (l lcs) reverse() {
+	for i, j := 0, len(l)-1; i < j; i, j = i+1, j-1 {
+		l[i], l[j] = l[j], l[i]
+	}
+}
+
 // validate that the elements of the lcs do not overlap
 // (can only happen when the two-sided algorithm ends early)
 // expects the lcs to be sorted
@@ -45,9 +61,9 @@ func (l lcs) valid() bool {
 	return true
 }
 
-// repair overlapping lcs
+// repair overlapping lcs, in the memory of tmp
 // only called if two-sided stops early
-func (l lcs) fix() lcs {
+func (l lcs) fix(tmp lcs) lcs {
 	// from the set of diagonals in l, find a maximal non-conflicting set
 	// this problem may be NP-complete, but we use a greedy heuristic,
 	// which is quadratic, but with a better data structure, could be D log D.
@@ -56,9 +72,8 @@ func (l lcs) fix() lcs {
 	if len(l) == 0 {
 		return nil
 	}
-	sort.Slice(l, func(i, j int) bool { return l[i].Len > l[j].Len })
-	tmp := make(lcs, 0, len(l))
-	tmp = append(tmp, l[0])
+	sort.Sort(byLen(l))
+	tmp = append(tmp[:0], l[0])
 	for i := 1; i < len(l); i++ {
 		var dir direction
 		nxt := l[i]
@@ -78,6 +93,13 @@ func (l lcs) fix() lcs {
 	return tmp
 }
 
+// byLen sorts an lcs inversely by Len.
+type byLen lcs
+
+func (l byLen) Len() int           { return len(l) }
+func (l byLen) Less(i, j int) bool { return l[i].Len > l[j].Len }
+func (l byLen) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
 type direction int
 
 const (
@@ -139,11 +161,12 @@ func overlap(exist, prop diag) (direction, diag) {
 // manipulating Diag and lcs
 
 // prepend a diagonal (x,y)-(x+1,y+1) segment either to an empty lcs
-// or to its first Diag. prepend is only called to extend diagonals
-// the backward direction.
-func (lcs lcs) prepend(x, y int) lcs {
-	if len(lcs) > 0 {
-		d := &lcs[0]
+// or to its first Diag. The lcs is lcs[start:], in reverse order so that
+// prepending to it appends to the slice; the caller reverses it when it is
+// done. prepend is only called to extend diagonals the backward direction.
+func (lcs lcs) prepend(start, x, y int) lcs {
+	if len(lcs) > start {
+		d := &lcs[len(lcs)-1]
 		if int(d.X) == x+1 && int(d.Y) == y+1 {
 			// extend the diagonal down and to the left
 			d.X, d.Y = int(x), int(y)
@@ -152,16 +175,14 @@ func (lcs lcs) prepend(x, y int) lcs {
 		}
 	}
 
-	r := diag{X: int(x), Y: int(y), Len: 1}
-	lcs = append([]diag{r}, lcs...)
-	return lcs
+	return append(lcs, diag{X: int(x), Y: int(y), Len: 1})
 }
 
-// append appends a diagonal, or extends the existing one.
-// by adding the edge (x,y)-(x+1.y+1). append is only called
+// append appends a diagonal to the lcs lcs[start:], or extends the existing
+// one. by adding the edge (x,y)-(x+1.y+1). append is only called
 // to extend diagonals in the forward direction.
-func (lcs lcs) append(x, y int) lcs {
-	if len(lcs) > 0 {
+func (lcs lcs) append(start, x, y int) lcs {
+	if len(lcs) > start {
 		last := &lcs[len(lcs)-1]
 		// Expand last element if adjoining.
 		if last.X+last.Len == x && last.Y+last.Len == y {
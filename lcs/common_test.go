@@ -127,7 +127,7 @@ func TestLcsFix(t *testing.T) {
 		{lcs{diag{0, 0, 4}, diag{1, 1, 6}, diag{3, 3, 2}}, lcs{diag{0, 0, 1}, diag{1, 1, 6}}},
 	}
 	for n, x := range tests {
-		got := x.before.fix()
+		got := x.before.fix(nil)
 		if len(got) != len(x.after) {
 			t.Errorf("got %v, expected %v, for %v", got, x.after, x.before)
 		}
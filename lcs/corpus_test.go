@@ -0,0 +1,13 @@
+package lcs_test
+
+import (
+	"testing"
+
+	"github.com/pgavlin/diff/difftest"
+)
+
+// TestCorpus compares the diff algorithms on the versions of a file in the
+// archive that git.sh builds, if it has been built.
+func TestCorpus(t *testing.T) {
+	difftest.CorpusTest(t, "testdata.zip", difftest.Algorithms)
+}
@@ -0,0 +1,166 @@
+package lcs
+
+import "github.com/pgavlin/text"
+
+// DiffTextMinimal is like DiffText, but always finds a longest common
+// subsequence, so the diffs are as small as possible. It does not respect
+// rune boundaries.
+//
+// Unlike an unbounded two-sided search, whose labels take memory quadratic
+// in the number of edits, it uses memory linear in the lengths of the
+// texts. It takes time proportional to the product of their total length
+// and the number of edits, so it is much slower than DiffText on large,
+// very different inputs.
+func DiffTextMinimal[S1, S2 text.String](a S1, b S2) []Diff {
+	return diffMinimal(textSeqs(a, b))
+}
+
+// DiffLinesMinimal is like DiffLines, but always finds a longest common
+// subsequence, as DiffTextMinimal does.
+func DiffLinesMinimal[S1, S2 text.String](a []S1, b []S2) []Diff {
+	return diffMinimal(lineSeqs[S1, S2]{a, b})
+}
+
+// DiffRunesMinimal is like DiffRunes, but always finds a longest common
+// subsequence, as DiffTextMinimal does.
+func DiffRunesMinimal(a, b []rune) []Diff {
+	return diffMinimal(runesSeqs{a, b})
+}
+
+func diffMinimal(seqs sequences) []Diff {
+	alen, blen := seqs.lengths()
+	return minimal(seqs).toDiffs(nil, alen, blen)
+}
+
+// minimal computes a longest common subsequence in linear space, by the
+// divide and conquer algorithm of section 4b of Myers' paper, in the
+// manner of Hirschberg: it finds the middle of an optimal path through the
+// edit graph with a forward and a backward search that keep only their
+// latest labels, then recurs on the rectangles before and after it.
+func minimal(seqs sequences) lcs {
+	alen, blen := seqs.lengths()
+	n := (alen+blen+1)/2 + 1
+	b := &bisector{seqs: seqs, vf: make([]int, 2*n+1), vb: make([]int, 2*n+1)}
+	b.diff(0, alen, 0, blen)
+	return b.lcs
+}
+
+// A bisector computes an lcs by bisecting the edit graph.
+type bisector struct {
+	seqs   sequences
+	vf, vb []int // the labels of the latest D of the forward and backward searches
+	lcs    lcs
+}
+
+// diff appends the lcs of A[ax:aj] and B[by:bj] to b.lcs.
+func (b *bisector) diff(ax, aj, by, bj int) {
+	if n := b.seqs.commonPrefixLen(ax, aj, by, bj); n > 0 {
+		b.add(ax, by, n)
+		ax, by = ax+n, by+n
+	}
+	suffix := b.seqs.commonSuffixLen(ax, aj, by, bj)
+	aj, bj = aj-suffix, bj-suffix
+
+	// Once the common prefix and suffix are gone, a rectangle that is empty
+	// in either direction has no diagonals. Otherwise at least two edits
+	// separate its corners, so both halves are smaller.
+	if ax < aj && by < bj {
+		x, y := b.middle(ax, aj, by, bj)
+		b.diff(ax, x, by, y)
+		b.diff(x, aj, y, bj)
+	}
+	if suffix > 0 {
+		b.add(aj, bj, suffix)
+	}
+}
+
+// add appends the diagonal of length n from (x,y) to b.lcs, joining it to
+// the last diagonal if they meet.
+func (b *bisector) add(x, y, n int) {
+	if last := len(b.lcs) - 1; last >= 0 && b.lcs[last].X+b.lcs[last].Len == x && b.lcs[last].Y+b.lcs[last].Len == y {
+		b.lcs[last].Len += n
+		return
+	}
+	b.lcs = append(b.lcs, diag{x, y, n})
+}
+
+// middle returns a vertex on an optimal path from (ax,by) to (aj,bj), other
+// than its corners. The rectangle must have no common prefix or suffix.
+//
+// The forward search labels diagonal k, relative to (ax,by), with the
+// largest relative x reached by a path of D edits. The backward search
+// labels diagonal k, relative to (aj,bj) and reversed, in the same way. If
+// delta, the difference of the diagonals of the corners, is odd, the paths
+// meet when a forward path of D edits reaches a backward path of D-1
+// edits, and otherwise when a backward path of D edits reaches a forward
+// path of D edits.
+func (b *bisector) middle(ax, aj, by, bj int) (int, int) {
+	n, m := aj-ax, bj-by
+	delta := n - m
+	odd := delta&1 != 0
+	maxD := (n + m + 1) / 2
+	off := maxD + 1 // the index of diagonal 0
+	vf, vb := b.vf[:2*off+1], b.vb[:2*off+1]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+
+	// Diagonals whose paths have left the rectangle are not extended;
+	// [-D+fstart, D-fend] are those of the forward search that remain.
+	var fstart, fend, bstart, bend int
+	for D := 0; D <= maxD; D++ {
+		for k := -D + fstart; k <= D-fend; k += 2 {
+			var x int
+			if k == -D || k != D && vf[off+k-1] < vf[off+k+1] {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			if x < n && y < m {
+				d := b.seqs.commonPrefixLen(ax+x, aj, by+y, bj)
+				x, y = x+d, y+d
+			}
+			vf[off+k] = x
+			switch {
+			case x > n:
+				fend += 2
+			case y > m:
+				fstart += 2
+			case odd:
+				if kb := off + delta - k; kb >= 0 && kb < len(vb) && vb[kb] != -1 && x >= n-vb[kb] {
+					return ax + x, by + y
+				}
+			}
+		}
+
+		for k := -D + bstart; k <= D-bend; k += 2 {
+			var x int
+			if k == -D || k != D && vb[off+k-1] < vb[off+k+1] {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			if x < n && y < m {
+				d := b.seqs.commonSuffixLen(ax, aj-x, by, bj-y)
+				x, y = x+d, y+d
+			}
+			vb[off+k] = x
+			switch {
+			case x > n:
+				bend += 2
+			case y > m:
+				bstart += 2
+			case !odd:
+				if kf := off + delta - k; kf >= 0 && kf < len(vf) && vf[kf] != -1 && vf[kf] >= n-x {
+					fx := vf[kf]
+					return ax + fx, by + fx - (kf - off)
+				}
+			}
+		}
+	}
+	// Not reached: the searches meet by D = maxD.
+	return aj, by
+}
@@ -0,0 +1,91 @@
+package lcs
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMinimal(t *testing.T) {
+	algo := func(e *editGraph) lcs { return minimal(e.seqs) }
+	for _, tx := range Btests {
+		diffs, lcs := compute(stringSeqs{tx.a, tx.b}, algo, 0)
+		check(t, tx.a, lcs, tx.lcs)
+		checkDiffs(t, tx.a, diffs, tx.b)
+
+		diffs, lcs = compute(stringSeqs{tx.b, tx.a}, algo, 0)
+		check(t, tx.b, lcs, tx.lcs)
+		checkDiffs(t, tx.b, diffs, tx.a)
+	}
+}
+
+// dynamicLCSLen returns the length of a longest common subsequence of a
+// and b by the textbook quadratic algorithm.
+func dynamicLCSLen(a, b []rune) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestMinimalRand(t *testing.T) {
+	rand.Seed(3)
+	for i := 0; i < 2000; i++ {
+		a := []rune(randstr("abω", rand.Intn(40)))
+		b := []rune(randstr("abωc", rand.Intn(40)))
+		lcs := minimal(runesSeqs{a, b})
+		if !lcs.valid() {
+			t.Fatalf("%q, %q: invalid lcs %v", string(a), string(b), lcs)
+		}
+		if got, want := lcslen(lcs), dynamicLCSLen(a, b); got != want {
+			t.Fatalf("%q, %q: got lcs of length %d, want %d", string(a), string(b), got, want)
+		}
+		if got, want := diffsCost(DiffRunesMinimal(a, b)), len(a)+len(b)-2*lcslen(lcs); got != want {
+			t.Fatalf("%q, %q: got diffs of cost %d, want %d", string(a), string(b), got, want)
+		}
+		checkDiffs(t, string(a), DiffTextMinimal(string(a), string(b)), string(b))
+	}
+}
+
+// TestMinimalDeep checks that the diffs of inputs that differ throughout
+// are as small as those of an unbounded search.
+func TestMinimalDeep(t *testing.T) {
+	rand.Seed(4)
+	a := strings.SplitAfter(randstr("abcdefgh\n", 3000), "\n")
+	b := strings.SplitAfter(randstr("abcdefgh\n", 3000), "\n")
+	want, truncated := DiffLinesBounded(a, b, Bounds{Limit: len(a) + len(b)})
+	if truncated {
+		t.Fatal("unbounded search ended early")
+	}
+	got := DiffLinesMinimal(a, b)
+	if !reflect.DeepEqual(applyLines(a, b, got), b) {
+		t.Fatal("the diffs do not transform a into b")
+	}
+	if g, w := diffsCost(got), diffsCost(want); g != w {
+		t.Errorf("got diffs of cost %d, want %d", g, w)
+	}
+
+	text := DiffTextMinimal(strings.Join(a, ""), strings.Join(b, ""))
+	checkDiffs(t, strings.Join(a, ""), text, strings.Join(b, ""))
+}
+
+// diffsCost returns the number of elements that diffs delete and insert.
+func diffsCost(diffs []Diff) int {
+	cost := 0
+	for _, d := range diffs {
+		cost += d.End - d.Start + d.ReplEnd - d.ReplStart
+	}
+	return cost
+}
@@ -35,21 +35,100 @@ func DiffAnySlices[T1, T2 any, S1 ~[]T1, S2 ~[]T2, C EqualsComparer[T1, T2]](a S
 
 // DiffText returns the differences between two texts.
 // It does not respect rune boundaries.
-func DiffText[S1, S2 text.String](a S1, b S2) []Diff { return diff(textSeqs(a, b)) }
+//
+// Texts that are short once their common prefix and suffix are removed
+// are diffed by a bit-parallel search, which is faster for them and always
+// finds a longest common subsequence.
+func DiffText[S1, S2 text.String](a S1, b S2) []Diff {
+	var s bitSearch
+	if lcs, ok := s.text(asString(a), asString(b)); ok {
+		return lcs.toDiffs(nil, len(a), len(b))
+	}
+	return diff(textSeqs(a, b))
+}
 
 // DiffLines returns the line differences between two texts.
 func DiffLines[S1, S2 text.String](a []S1, b []S2) []Diff { return diff(lineSeqs[S1, S2]{a, b}) }
 
-// DiffRunes returns the differences between two rune sequences.
-func DiffRunes(a, b []rune) []Diff { return diff(runesSeqs{a, b}) }
+// DiffRunes returns the differences between two rune sequences. Like
+// DiffText, it uses a bit-parallel search for short sequences.
+func DiffRunes(a, b []rune) []Diff {
+	var s bitSearch
+	if lcs, ok := s.runes(a, b); ok {
+		return lcs.toDiffs(nil, len(a), len(b))
+	}
+	return diff(runesSeqs{a, b})
+}
+
+// A limit on how deeply the LCS algorithm should search. The value is just a guess.
+const maxDiffs = 30
 
 func diff(seqs sequences) []Diff {
-	// A limit on how deeply the LCS algorithm should search. The value is just a guess.
-	const maxDiffs = 30
-	diff, _ := compute(seqs, twosided, maxDiffs/2)
+	diff, _ := diffBounded(seqs, Bounds{})
 	return diff
 }
 
+// Bounds limit the search for a longest common subsequence. When the search
+// gives up, the diffs are computed from the part of a longest common
+// subsequence that it has found, so they are valid but may be larger than
+// necessary.
+type Bounds struct {
+	// Limit is the number of edits that the search explores from each end
+	// of the sequences. If zero, a small default is used.
+	Limit int
+	// Stop, if not nil, is called before each step of the search, and ends
+	// the search if it returns true.
+	Stop func() bool
+}
+
+// DiffTextBounded is like DiffText, but bounds the search. It also reports
+// whether the search ended early.
+//
+// If bounds.Limit is zero, short texts are diffed by the bit-parallel
+// search of DiffText, whose size is bounded. It calls bounds.Stop only
+// before it begins.
+func DiffTextBounded[S1, S2 text.String](a S1, b S2, bounds Bounds) ([]Diff, bool) {
+	if bounds.Limit <= 0 && (bounds.Stop == nil || !bounds.Stop()) {
+		var s bitSearch
+		if lcs, ok := s.text(asString(a), asString(b)); ok {
+			return lcs.toDiffs(nil, len(a), len(b)), false
+		}
+	}
+	return diffBounded(textSeqs(a, b), bounds)
+}
+
+// DiffLinesBounded is like DiffLines, but bounds the search. It also
+// reports whether the search ended early.
+func DiffLinesBounded[S1, S2 text.String](a []S1, b []S2, bounds Bounds) ([]Diff, bool) {
+	return diffBounded(lineSeqs[S1, S2]{a, b}, bounds)
+}
+
+// DiffRunesBounded is like DiffRunes, but bounds the search as
+// DiffTextBounded does. It also reports whether the search ended early.
+func DiffRunesBounded(a, b []rune, bounds Bounds) ([]Diff, bool) {
+	if bounds.Limit <= 0 && (bounds.Stop == nil || !bounds.Stop()) {
+		var s bitSearch
+		if lcs, ok := s.runes(a, b); ok {
+			return lcs.toDiffs(nil, len(a), len(b)), false
+		}
+	}
+	return diffBounded(runesSeqs{a, b}, bounds)
+}
+
+// diffBounded computes the differences between two sequences, and reports
+// whether the search ended early.
+func diffBounded(seqs sequences, bounds Bounds) ([]Diff, bool) {
+	limit := bounds.Limit
+	if limit <= 0 {
+		limit = maxDiffs / 2
+	}
+	g := newEditGraph(seqs, limit)
+	g.stop = bounds.Stop
+	lcs := twosided(g)
+	alen, blen := seqs.lengths()
+	return lcs.toDiffs(nil, alen, blen), g.truncated
+}
+
 // compute computes the list of differences between two sequences,
 // along with the LCS. It is exercised directly by tests.
 // The algorithm is one of {forward, backward, twosided}.
@@ -57,21 +136,35 @@ func compute(seqs sequences, algo func(*editGraph) lcs, limit int) ([]Diff, lcs)
 	if limit <= 0 {
 		limit = 1 << 25 // effectively infinity
 	}
+	lcs := algo(newEditGraph(seqs, limit))
 	alen, blen := seqs.lengths()
-	g := &editGraph{
-		seqs:  seqs,
-		vf:    newtriang(limit),
-		vb:    newtriang(limit),
-		limit: limit,
-		ux:    alen,
-		uy:    blen,
-		delta: alen - blen,
-	}
-	lcs := algo(g)
-	diffs := lcs.toDiffs(alen, blen)
+	diffs := lcs.toDiffs(nil, alen, blen)
 	return diffs, lcs
 }
 
+func newEditGraph(seqs sequences, limit int) *editGraph {
+	e := &editGraph{vf: newtriang(limit), vb: newtriang(limit)}
+	e.reset(seqs, limit)
+	return e
+}
+
+// reset prepares e to compute the lcs of seqs, keeping the memory of its
+// labels and lcs.
+func (e *editGraph) reset(seqs sequences, limit int) {
+	alen, blen := seqs.lengths()
+	*e = editGraph{
+		seqs:   seqs,
+		vf:     e.vf,
+		vb:     e.vb,
+		limit:  limit,
+		ux:     alen,
+		uy:     blen,
+		delta:  alen - blen,
+		buf:    e.buf,
+		fixBuf: e.fixBuf,
+	}
+}
+
 // editGraph carries the information for computing the lcs of two sequences.
 type editGraph struct {
 	seqs   sequences
@@ -81,11 +174,28 @@ type editGraph struct {
 	// the bounding rectangle of the current edit graph
 	lx, ly, ux, uy int
 	delta          int // common subexpression: (ux-lx)-(uy-ly)
+
+	stop      func() bool // if not nil, ends the search early
+	truncated bool        // whether the search ended before finding the lcs
+
+	buf    lcs // memory for the lcs, which a Scratch reuses
+	fixBuf lcs // memory for the repaired lcs of a truncated search
+}
+
+// halt reports whether the search should end before step D because stop
+// says so. If it should, halt lowers the limit to D, so that a partial lcs
+// is recovered from the labels of D as if D were the limit.
+func (e *editGraph) halt(D int) bool {
+	if e.stop != nil && e.stop() {
+		e.limit = D
+		return true
+	}
+	return false
 }
 
-// toDiffs converts an LCS to a list of edits.
-func (lcs lcs) toDiffs(alen, blen int) []Diff {
-	var diffs []Diff
+// toDiffs converts an LCS to a list of edits, and appends them to dst.
+func (lcs lcs) toDiffs(dst []Diff, alen, blen int) []Diff {
+	diffs := dst
 	var pa, pb int // offsets in a, b
 	for _, l := range lcs {
 		if pa < l.X || pb < l.Y {
@@ -109,7 +219,7 @@ func (e *editGraph) fdone(D, k int) (bool, lcs) {
 	x := e.vf.get(D, k)
 	y := x - k
 	if x == e.ux && y == e.uy {
-		return true, e.forwardlcs(D, k)
+		return true, e.forwardlcs(nil, D, k)
 	}
 	return false, nil
 }
@@ -122,6 +232,9 @@ func forward(e *editGraph) lcs {
 	}
 	// from D to D+1
 	for D := 0; D < e.limit; D++ {
+		if e.halt(D) {
+			break
+		}
 		e.setForward(D+1, -(D + 1), e.getForward(D, -D))
 		if ok, ans := e.fdone(D+1, -(D + 1)); ok {
 			return ans
@@ -147,6 +260,7 @@ func forward(e *editGraph) lcs {
 	// D is too large
 	// find the D path with maximal x+y inside the rectangle and
 	// use that to compute the found part of the lcs
+	e.truncated = true
 	kmax := -e.limit - 1
 	diagmax := -1
 	for k := -e.limit; k <= e.limit; k += 2 {
@@ -156,12 +270,13 @@ func forward(e *editGraph) lcs {
 			diagmax, kmax = x+y, k
 		}
 	}
-	return e.forwardlcs(e.limit, kmax)
+	return e.forwardlcs(nil, e.limit, kmax)
 }
 
-// recover the lcs by backtracking from the farthest point reached
-func (e *editGraph) forwardlcs(D, k int) lcs {
-	var ans lcs
+// recover the lcs by backtracking from the farthest point reached,
+// and append it to dst
+func (e *editGraph) forwardlcs(dst lcs, D, k int) lcs {
+	ans, start := dst, len(dst)
 	for x := e.getForward(D, k); x != 0 || x-k != 0; {
 		if ok(D-1, k-1) && x-1 == e.getForward(D-1, k-1) {
 			// if (x-1,y) is labelled D-1, x--,D--,k--,continue
@@ -174,9 +289,10 @@ func (e *editGraph) forwardlcs(D, k int) lcs {
 		}
 		// if (x-1,y-1)--(x,y) is a diagonal, prepend,x--,y--, continue
 		y := x - k
-		ans = ans.prepend(x+e.lx-1, y+e.ly-1)
+		ans = ans.prepend(start, x+e.lx-1, y+e.ly-1)
 		x--
 	}
+	ans[start:].reverse()
 	return ans
 }
 
@@ -209,7 +325,7 @@ func (e *editGraph) bdone(D, k int) (bool, lcs) {
 	x := e.vb.get(D, k)
 	y := x - (k + e.delta)
 	if x == 0 && y == 0 {
-		return true, e.backwardlcs(D, k)
+		return true, e.backwardlcs(nil, D, k)
 	}
 	return false, nil
 }
@@ -222,6 +338,9 @@ func backward(e *editGraph) lcs {
 	}
 	// from D to D+1
 	for D := 0; D < e.limit; D++ {
+		if e.halt(D) {
+			break
+		}
 		e.setBackward(D+1, -(D + 1), e.getBackward(D, -D)-1)
 		if ok, ans := e.bdone(D+1, -(D + 1)); ok {
 			return ans
@@ -248,6 +367,7 @@ func backward(e *editGraph) lcs {
 	// D is too large
 	// find the D path with minimal x+y inside the rectangle and
 	// use that to compute the part of the lcs found
+	e.truncated = true
 	kmax := -e.limit - 1
 	diagmin := 1 << 25
 	for k := -e.limit; k <= e.limit; k += 2 {
@@ -260,12 +380,12 @@ func backward(e *editGraph) lcs {
 	if kmax < -e.limit {
 		panic(fmt.Sprintf("no paths when limit=%d?", e.limit))
 	}
-	return e.backwardlcs(e.limit, kmax)
+	return e.backwardlcs(nil, e.limit, kmax)
 }
 
-// recover the lcs by backtracking
-func (e *editGraph) backwardlcs(D, k int) lcs {
-	var ans lcs
+// recover the lcs by backtracking, and append it to dst
+func (e *editGraph) backwardlcs(dst lcs, D, k int) lcs {
+	ans, start := dst, len(dst)
 	for x := e.getBackward(D, k); x != e.ux || x-(k+e.delta) != e.uy; {
 		if ok(D-1, k-1) && x == e.getBackward(D-1, k-1) {
 			// D--, k--, x unchanged
@@ -277,7 +397,7 @@ func (e *editGraph) backwardlcs(D, k int) lcs {
 			continue
 		}
 		y := x - (k + e.delta)
-		ans = ans.append(x+e.lx, y+e.ly)
+		ans = ans.append(start, x+e.lx, y+e.ly)
 		x++
 	}
 	return ans
@@ -320,6 +440,9 @@ func twosided(e *editGraph) lcs {
 		if got, ok := e.twoDone(D, D); ok {
 			return e.twolcs(D, D, got)
 		}
+		if e.halt(D) {
+			break
+		}
 		// do a forwards pass (D to D+1)
 		e.setForward(D+1, -(D + 1), e.getForward(D, -D))
 		e.setForward(D+1, D+1, e.getForward(D, D)+1)
@@ -354,6 +477,7 @@ func twosided(e *editGraph) lcs {
 
 	// D too large. combine a forward and backward partial lcs
 	// first, a forward one
+	e.truncated = true
 	kmax := -e.limit - 1
 	diagmax := -1
 	for k := -e.limit; k <= e.limit; k += 2 {
@@ -366,7 +490,7 @@ func twosided(e *editGraph) lcs {
 	if kmax < -e.limit {
 		panic(fmt.Sprintf("no forward paths when limit=%d?", e.limit))
 	}
-	lcs := e.forwardlcs(e.limit, kmax)
+	lcs := e.forwardlcs(e.buf[:0], e.limit, kmax)
 	// now a backward one
 	// find the D path with minimal x+y inside the rectangle and
 	// use that to compute the lcs
@@ -381,9 +505,11 @@ func twosided(e *editGraph) lcs {
 	if kmax < -e.limit {
 		panic(fmt.Sprintf("no backward paths when limit=%d?", e.limit))
 	}
-	lcs = append(lcs, e.backwardlcs(e.limit, kmax)...)
+	lcs = e.backwardlcs(lcs, e.limit, kmax)
 	// These may overlap (e.forwardlcs and e.backwardlcs return sorted lcs)
-	ans := lcs.fix()
+	e.buf = lcs[:0]
+	ans := lcs.fix(e.fixBuf)
+	e.fixBuf = ans[:0]
 	return ans
 }
 
@@ -439,8 +565,8 @@ func (e *editGraph) twolcs(df, db, kf int) lcs {
 	if x == u {
 		// "babaab" "cccaba"
 		// already patched together
-		lcs := e.forwardlcs(df, kf)
-		lcs = append(lcs, e.backwardlcs(db, kb)...)
+		lcs := e.forwardlcs(e.buf[:0], df, kf)
+		lcs = e.backwardlcs(lcs, db, kb)
 		return lcs.sort()
 	}
 
@@ -449,14 +575,14 @@ func (e *editGraph) twolcs(df, db, kf int) lcs {
 	// is the df-path to (u,v), then plus the db-path to (N,M)
 	if u > 0 && ok(df-1, u-1-v) && e.vf.get(df-1, u-1-v) == u-1 {
 		//  "aabbab" "cbcabc"
-		lcs := e.forwardlcs(df-1, u-1-v)
-		lcs = append(lcs, e.backwardlcs(db, kb)...)
+		lcs := e.forwardlcs(e.buf[:0], df-1, u-1-v)
+		lcs = e.backwardlcs(lcs, db, kb)
 		return lcs.sort()
 	}
 	if v > 0 && ok(df-1, (u-(v-1))) && e.vf.get(df-1, u-(v-1)) == u {
 		//  "abaabb" "bcacab"
-		lcs := e.forwardlcs(df-1, u-(v-1))
-		lcs = append(lcs, e.backwardlcs(db, kb)...)
+		lcs := e.forwardlcs(e.buf[:0], df-1, u-(v-1))
+		lcs = e.backwardlcs(lcs, db, kb)
 		return lcs.sort()
 	}
 
@@ -465,28 +591,28 @@ func (e *editGraph) twolcs(df, db, kf int) lcs {
 	if u == 0 || v == 0 || x == e.ux || y == e.uy {
 		// "abaabb" "abaaaa"
 		if u == 0 || v == 0 {
-			return e.backwardlcs(db, kb)
+			return e.backwardlcs(e.buf[:0], db, kb)
 		}
-		return e.forwardlcs(df, kf)
+		return e.forwardlcs(e.buf[:0], df, kf)
 	}
 
 	// is (x+1,y) or (x,y+1) labelled db-1?
 	if x+1 <= e.ux && ok(db-1, x+1-y-e.delta) && e.vb.get(db-1, x+1-y-e.delta) == x+1 {
 		// "bababb" "baaabb"
-		lcs := e.backwardlcs(db-1, kb+1)
-		lcs = append(lcs, e.forwardlcs(df, kf)...)
+		lcs := e.backwardlcs(e.buf[:0], db-1, kb+1)
+		lcs = e.forwardlcs(lcs, df, kf)
 		return lcs.sort()
 	}
 	if y+1 <= e.uy && ok(db-1, x-(y+1)-e.delta) && e.vb.get(db-1, x-(y+1)-e.delta) == x {
 		// "abbbaa" "cabacc"
-		lcs := e.backwardlcs(db-1, kb-1)
-		lcs = append(lcs, e.forwardlcs(df, kf)...)
+		lcs := e.backwardlcs(e.buf[:0], db-1, kb-1)
+		lcs = e.forwardlcs(lcs, df, kf)
 		return lcs.sort()
 	}
 
 	// need to compute another path
 	// "aabbaa" "aacaba"
-	lcs := e.backwardlcs(db, kb)
+	lcs := e.backwardlcs(e.buf[:0], db, kb)
 	oldx, oldy := e.ux, e.uy
 	e.ux = u
 	e.uy = v
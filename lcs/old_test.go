@@ -175,6 +175,30 @@ func TestDiffAPI(t *testing.T) {
 	}
 }
 
+func TestDiffBounded(t *testing.T) {
+	a, b := strings.Repeat("ab", 50), strings.Repeat("ba", 50)+"c"
+	if diffs, truncated := DiffTextBounded(a, b, Bounds{Limit: 200}); truncated {
+		t.Errorf("a large limit truncated the search")
+	} else {
+		checkDiffs(t, a, diffs, b)
+	}
+
+	a, b = randstr("abc", 200), randstr("abc", 200)
+	diffs, truncated := DiffTextBounded(a, b, Bounds{Limit: 2})
+	if !truncated {
+		t.Errorf("a small limit did not truncate the search")
+	}
+	checkDiffs(t, a, diffs, b)
+
+	calls := 0
+	stop := func() bool { calls++; return true }
+	diffs, truncated = DiffTextBounded(a, b, Bounds{Stop: stop})
+	if !truncated || calls == 0 {
+		t.Errorf("got truncated=%v after %d calls of Stop", truncated, calls)
+	}
+	checkDiffs(t, a, diffs, b)
+}
+
 func BenchmarkTwoOld(b *testing.B) {
 	tests := genBench("abc", 96)
 	for i := 0; i < b.N; i++ {
@@ -251,6 +275,14 @@ func BenchmarkLargeFileSmallDiff(b *testing.B) {
 		}
 	})
 
+	// The default search follows the long common prefix and suffix of the
+	// texts, which are scanned a word at a time.
+	b.Run("default", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			DiffText(src, dst)
+		}
+	})
+
 	srcBytes := []byte(src)
 	dstBytes := []byte(dst)
 	b.Run("bytes", func(b *testing.B) {
@@ -0,0 +1,256 @@
+package lcs
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pgavlin/text"
+)
+
+// minSegmentLines is the least number of lines in each input that
+// DiffLinesParallel puts in a segment, as smaller segments cost more to
+// schedule than to diff.
+const minSegmentLines = 1000
+
+// DiffLinesParallel is like DiffLines, but diffs large inputs on up to
+// workers goroutines at once. If workers is zero or negative,
+// runtime.GOMAXPROCS(0) is used.
+//
+// It splits the inputs at anchors: lines that occur exactly once in each
+// input, in the same order, as in patience diff. The segments between
+// anchors are independent, so they are diffed concurrently, and their
+// diffs joined. Within each segment, the lines between its own anchors are
+// diffed by DiffLines. The diffs transform a into b exactly, but may differ
+// from those of DiffLines, whose search does not necessarily match the
+// anchors. As the gaps between anchors are small, the diffs of huge inputs
+// are usually much smaller than those of DiffLines, whose search is
+// limited, at the cost of hashing every line.
+func DiffLinesParallel[S1, S2 text.String](a []S1, b []S2, workers int) []Diff {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	segments := splitAtAnchors(a, b, workers)
+	if len(segments) < 2 {
+		return diffAnchored(a, b)
+	}
+
+	results := make([][]Diff, len(segments))
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(segments); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(segments) {
+					return
+				}
+				s := segments[i]
+				results[i] = diffAnchored(a[s.a0:s.a1], b[s.b0:s.b1])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var diffs []Diff
+	for i, s := range segments {
+		diffs = appendDiffs(diffs, results[i], s.a0, s.b0)
+	}
+	return diffs
+}
+
+// diffAnchored computes the differences between two sequences of lines by
+// matching their anchors, then diffing the lines between each pair of
+// anchors.
+func diffAnchored[S1, S2 text.String](a []S1, b []S2) []Diff {
+	var diffs []Diff
+	var s Scratch
+	gap := func(a0, a1, b0, b1 int) {
+		switch {
+		case a0 == a1 && b0 == b1:
+		case a0 == a1 || b0 == b1:
+			diffs = appendDiffs(diffs, []Diff{{Start: 0, End: a1 - a0, ReplStart: 0, ReplEnd: b1 - b0}}, a0, b0)
+		default:
+			diffs = appendDiffs(diffs, DiffLinesScratch(a[a0:a1], b[b0:b1], &s), a0, b0)
+		}
+	}
+	var pa, pb int // offsets in a, b
+	for _, anchor := range increasing(uniqueLines(a, b)) {
+		gap(pa, anchor.a, pb, anchor.b)
+		pa, pb = anchor.a+1, anchor.b+1
+	}
+	gap(pa, len(a), pb, len(b))
+	return diffs
+}
+
+// appendDiffs appends to dst the diffs of the parts of A and B that begin
+// at a0 and b0, joining diffs that meet.
+func appendDiffs(dst, diffs []Diff, a0, b0 int) []Diff {
+	for _, d := range diffs {
+		d.Start, d.End = d.Start+a0, d.End+a0
+		d.ReplStart, d.ReplEnd = d.ReplStart+b0, d.ReplEnd+b0
+		if n := len(dst); n > 0 && dst[n-1].End == d.Start && dst[n-1].ReplEnd == d.ReplStart {
+			dst[n-1].End, dst[n-1].ReplEnd = d.End, d.ReplEnd
+			continue
+		}
+		dst = append(dst, d)
+	}
+	return dst
+}
+
+// A segment is a part a[a0:a1] of A to be diffed with the part b[b0:b1] of B.
+type segment struct {
+	a0, a1, b0, b1 int
+}
+
+// anchorWindow is the number of lines around each desired boundary of a
+// segment that are candidates for its anchor.
+const anchorWindow = 64
+
+// splitAtAnchors splits a and b into segments that begin with anchors, of
+// at least minSegmentLines lines each and about four for each worker.
+func splitAtAnchors[S1, S2 text.String](a []S1, b []S2, workers int) []segment {
+	size := len(a) / (4 * workers)
+	if size < minSegmentLines {
+		size = minSegmentLines
+	}
+	if len(a) < 2*size && len(b) < 2*size {
+		return nil
+	}
+
+	var segments []segment
+	start := segment{}
+	for _, anchor := range anchors(a, b, size) {
+		if anchor.a-start.a0 >= size/2 && anchor.b-start.b0 > 0 {
+			start.a1, start.b1 = anchor.a, anchor.b
+			segments = append(segments, start)
+			start = segment{a0: anchor.a, b0: anchor.b}
+		}
+	}
+	start.a1, start.b1 = len(a), len(b)
+	return append(segments, start)
+}
+
+// An anchor is a line that occurs once in each of A and B, at A[a] and B[b].
+type anchor struct {
+	a, b int
+}
+
+// anchors returns anchors near every size lines of a, in increasing order
+// in both a and b.
+//
+// Only the lines in a window around each boundary are candidates, so that
+// finding the anchors of a huge input costs a pass over it with a small
+// table rather than a table of all of its lines.
+func anchors[S1, S2 text.String](a []S1, b []S2, size int) []anchor {
+	type candidate struct {
+		na, nb int
+		a, b   int
+	}
+	var candidates []candidate
+	index := map[string]int{}
+	for mid := size; mid < len(a); mid += size {
+		lo, hi := mid-anchorWindow/2, mid+anchorWindow/2
+		if hi > len(a) {
+			hi = len(a)
+		}
+		for _, line := range a[lo:hi] {
+			if _, ok := index[asString(line)]; !ok {
+				index[asString(line)] = len(candidates)
+				candidates = append(candidates, candidate{})
+			}
+		}
+	}
+
+	for i, line := range a {
+		if k, ok := index[asString(line)]; ok {
+			c := &candidates[k]
+			c.na, c.a = c.na+1, i
+		}
+	}
+	for i, line := range b {
+		if k, ok := index[asString(line)]; ok {
+			c := &candidates[k]
+			c.nb, c.b = c.nb+1, i
+		}
+	}
+
+	// The unique lines, in the order of A.
+	var unique []anchor
+	for _, c := range candidates {
+		if c.na == 1 && c.nb == 1 {
+			unique = append(unique, anchor{c.a, c.b})
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].a < unique[j].a })
+	return increasing(unique)
+}
+
+// uniqueLines returns the lines that occur once in each of a and b, in
+// the order of a.
+func uniqueLines[S1, S2 text.String](a []S1, b []S2) []anchor {
+	type count struct {
+		na, nb int
+		b      int
+	}
+	var counts []count
+	index := make(map[string]int, len(a))
+	ids := make([]int, len(a))
+	for i, line := range a {
+		k, ok := index[asString(line)]
+		if !ok {
+			k = len(counts)
+			index[asString(line)] = k
+			counts = append(counts, count{})
+		}
+		counts[k].na++
+		ids[i] = k
+	}
+	for i, line := range b {
+		if k, ok := index[asString(line)]; ok {
+			counts[k].nb, counts[k].b = counts[k].nb+1, i
+		}
+	}
+
+	var unique []anchor
+	for i, k := range ids {
+		if c := counts[k]; c.na == 1 && c.nb == 1 {
+			unique = append(unique, anchor{i, c.b})
+		}
+	}
+	return unique
+}
+
+// increasing returns the longest subsequence of anchors, which are in
+// increasing order in A, that is also in increasing order in B. It finds
+// it by patience sorting: tails[k] is the index of the anchor with the
+// least B that ends an increasing subsequence of length k+1, and prev
+// links each anchor to the one before it.
+func increasing(anchors []anchor) []anchor {
+	var tails []int
+	prev := make([]int, len(anchors))
+	for i, u := range anchors {
+		k := sort.Search(len(tails), func(k int) bool { return anchors[tails[k]].b > u.b })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	if len(tails) == 0 {
+		return nil
+	}
+	lis := make([]anchor, len(tails))
+	for i, k := tails[len(tails)-1], len(lis)-1; k >= 0; i, k = prev[i], k-1 {
+		lis[k] = anchors[i]
+	}
+	return lis
+}
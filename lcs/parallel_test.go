@@ -0,0 +1,108 @@
+package lcs
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// editLines returns a file of n lines, many of them repeated, and a copy
+// of it with about one line in a hundred inserted, deleted or replaced.
+func editLines(rng *rand.Rand, n int) (a, b []string) {
+	for i := 0; i < n; i++ {
+		if rng.Intn(3) == 0 {
+			a = append(a, "}\n")
+		} else {
+			a = append(a, fmt.Sprintf("line %d\n", i))
+		}
+	}
+	for i, line := range a {
+		switch rng.Intn(300) {
+		case 0: // delete
+		case 1: // insert
+			b = append(b, fmt.Sprintf("new %d\n", i), line)
+		case 2: // replace
+			b = append(b, "}\n")
+		default:
+			b = append(b, line)
+		}
+	}
+	return a, b
+}
+
+// applyLines applies diffs to the lines of a.
+func applyLines(a, b []string, diffs []Diff) []string {
+	var out []string
+	last := 0
+	for _, d := range diffs {
+		out = append(out, a[last:d.Start]...)
+		out = append(out, b[d.ReplStart:d.ReplEnd]...)
+		last = d.End
+	}
+	return append(out, a[last:]...)
+}
+
+func TestDiffLinesParallel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var a, b []string
+	for _, n := range []int{0, 10, 3000, 20000} {
+		a, b = editLines(rng, n)
+		for _, workers := range []int{1, 4} {
+			diffs := DiffLinesParallel(a, b, workers)
+			if got := applyLines(a, b, diffs); !reflect.DeepEqual(got, b) && len(got)+len(b) > 0 {
+				t.Errorf("n=%d, workers=%d: applying the diffs does not give b", n, workers)
+			}
+			for i := 1; i < len(diffs); i++ {
+				if diffs[i].Start < diffs[i-1].End || diffs[i].Start == diffs[i-1].End && diffs[i].ReplStart == diffs[i-1].ReplEnd {
+					t.Errorf("n=%d, workers=%d: diffs %v and %v are out of order or adjacent", n, workers, diffs[i-1], diffs[i])
+				}
+			}
+		}
+	}
+
+	// Without unique lines, there are no anchors at which to split.
+	a = make([]string, 5000)
+	for i := range a {
+		a[i] = "}\n"
+	}
+	b = a[:4000]
+	if segments := splitAtAnchors(a, b, 4); len(segments) > 1 {
+		t.Errorf("lines without anchors were split into %d segments", len(segments))
+	}
+	if got := applyLines(a, b, DiffLinesParallel(a, b, 4)); !reflect.DeepEqual(got, b) {
+		t.Errorf("applying the diffs of lines without anchors does not give b")
+	}
+}
+
+func TestSplitAtAnchors(t *testing.T) {
+	a, b := editLines(rand.New(rand.NewSource(2)), 20000)
+	segments := splitAtAnchors(a, b, 2)
+	if len(segments) < 8 {
+		t.Fatalf("got %d segments, want at least 8", len(segments))
+	}
+	var aEnd, bEnd int
+	for i, s := range segments {
+		if s.a0 != aEnd || s.b0 != bEnd {
+			t.Fatalf("segment %d is %+v, want it to start at %d, %d", i, s, aEnd, bEnd)
+		}
+		if i > 0 && a[s.a0] != b[s.b0] {
+			t.Errorf("segment %d does not start with an anchor: %q, %q", i, a[s.a0], b[s.b0])
+		}
+		aEnd, bEnd = s.a1, s.b1
+	}
+	if aEnd != len(a) || bEnd != len(b) {
+		t.Errorf("segments end at %d, %d, want %d, %d", aEnd, bEnd, len(a), len(b))
+	}
+}
+
+func TestIncreasing(t *testing.T) {
+	anchors := []anchor{{0, 3}, {1, 1}, {2, 2}, {3, 0}, {4, 4}}
+	want := []anchor{{1, 1}, {2, 2}, {4, 4}}
+	if got := increasing(anchors); !reflect.DeepEqual(got, want) {
+		t.Errorf("got anchors %v, want %v", got, want)
+	}
+	if got := increasing(nil); got != nil {
+		t.Errorf("got anchors %v for none", got)
+	}
+}
@@ -0,0 +1,73 @@
+package lcs
+
+import "github.com/pgavlin/text"
+
+// A Scratch holds memory that successive diffs reuse: the labels of the
+// search, the longest common subsequence and the diffs themselves. A
+// program that computes many diffs can use a Scratch to avoid allocating
+// for each of them. The zero Scratch is ready to use. A Scratch must not be
+// used by more than one diff at a time.
+type Scratch struct {
+	g       editGraph
+	strings stringSeqs
+	runes   runesSeqs
+	lines   any // the *lineSeqs for the types of the most recent lines
+	bits    bitSearch
+	diffs   []Diff
+}
+
+// DiffTextScratch is like DiffText, but reuses the memory of s. The diffs
+// are only valid until s is used again.
+func DiffTextScratch[S1, S2 text.String](a S1, b S2, s *Scratch) []Diff {
+	if lcs, ok := s.bits.text(asString(a), asString(b)); ok {
+		s.diffs = lcs.toDiffs(s.diffs[:0], len(a), len(b))
+		return s.diffs
+	}
+	s.strings = stringSeqs{a: asString(a), b: asString(b)}
+	diffs := s.diff(&s.strings)
+	s.strings = stringSeqs{}
+	return diffs
+}
+
+// DiffLinesScratch is like DiffLines, but reuses the memory of s. The
+// diffs are only valid until s is used again.
+func DiffLinesScratch[S1, S2 text.String](a []S1, b []S2, s *Scratch) []Diff {
+	seqs, ok := s.lines.(*lineSeqs[S1, S2])
+	if !ok {
+		seqs = &lineSeqs[S1, S2]{}
+		s.lines = seqs
+	}
+	seqs.a, seqs.b = a, b
+	diffs := s.diff(seqs)
+	seqs.a, seqs.b = nil, nil
+	return diffs
+}
+
+// DiffRunesScratch is like DiffRunes, but reuses the memory of s. The
+// diffs are only valid until s is used again.
+func DiffRunesScratch(a, b []rune, s *Scratch) []Diff {
+	if lcs, ok := s.bits.runes(a, b); ok {
+		s.diffs = lcs.toDiffs(s.diffs[:0], len(a), len(b))
+		return s.diffs
+	}
+	s.runes = runesSeqs{a, b}
+	diffs := s.diff(&s.runes)
+	s.runes = runesSeqs{}
+	return diffs
+}
+
+// diff computes the differences between two sequences in the memory of s.
+// It does not retain seqs.
+func (s *Scratch) diff(seqs sequences) []Diff {
+	s.g.reset(seqs, maxDiffs/2)
+	lcs := twosided(&s.g)
+	alen, blen := seqs.lengths()
+	s.diffs = lcs.toDiffs(s.diffs[:0], alen, blen)
+	if !s.g.truncated && cap(lcs) > cap(s.g.buf) {
+		// Keep the grown memory of the lcs. twosided keeps it itself
+		// when truncated, as the repaired lcs is in other memory.
+		s.g.buf = lcs[:0]
+	}
+	s.g.seqs, s.g.stop = nil, nil
+	return s.diffs
+}
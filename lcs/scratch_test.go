@@ -0,0 +1,49 @@
+package lcs
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScratch(t *testing.T) {
+	rand.Seed(3)
+	var s Scratch
+	for i := 0; i < 500; i++ {
+		// Vary the sizes so that the scratch memory both grows and is
+		// reused for smaller diffs.
+		n := rand.Intn(64)
+		a, b := randstr("abω\n", n), randstr("abωc\n", rand.Intn(64))
+
+		if got, want := DiffTextScratch(a, b, &s), DiffText(a, b); !equalDiffs(got, want) {
+			t.Fatalf("DiffTextScratch(%q, %q) = %v, want %v", a, b, got, want)
+		}
+		ra, rb := []rune(a), []rune(b)
+		if got, want := DiffRunesScratch(ra, rb, &s), DiffRunes(ra, rb); !equalDiffs(got, want) {
+			t.Fatalf("DiffRunesScratch(%q, %q) = %v, want %v", a, b, got, want)
+		}
+		la, lb := strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")
+		if got, want := DiffLinesScratch(la, lb, &s), DiffLines(la, lb); !equalDiffs(got, want) {
+			t.Fatalf("DiffLinesScratch(%q, %q) = %v, want %v", la, lb, got, want)
+		}
+	}
+}
+
+func equalDiffs(a, b []Diff) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+func TestScratchAllocs(t *testing.T) {
+	a := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 20)
+	b := strings.Replace(a, "lazy", "sleepy", 3)
+	la, lb := strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")
+
+	var s Scratch
+	if n := testing.AllocsPerRun(100, func() { DiffTextScratch(a, b, &s) }); n != 0 {
+		t.Errorf("DiffTextScratch allocates %v times per run", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { DiffLinesScratch(la, lb, &s) }); n != 0 {
+		t.Errorf("DiffLinesScratch allocates %v times per run", n)
+	}
+}
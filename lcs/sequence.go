@@ -5,6 +5,7 @@
 package lcs
 
 import (
+	"math/bits"
 	"unsafe"
 
 	"github.com/pgavlin/text"
@@ -99,17 +100,24 @@ func (s runesSeqs) commonSuffixLen(ai, aj, bi, bj int) int {
 	return commonSuffixLenRunes(s.a[ai:aj:aj], s.b[bi:bj:bj])
 }
 
-// TODO(adonovan): optimize these functions using ideas from:
-// - https://go.dev/cl/408116 common.go
-// - https://go.dev/cl/421435 xor_generic.go
-
 // TODO(adonovan): factor using generics when available,
 // but measure performance impact.
 
 // commonPrefixLen* returns the length of the common prefix of a[ai:aj] and b[bi:bj].
+//
+// The byte and string variants compare eight bytes at a time: the lowest
+// set bit of the XOR of two words locates the first differing byte.
 func commonPrefixLenBytes(a, b []byte) int {
 	n := min(len(a), len(b))
+	if n == 0 || a[0] != b[0] {
+		return 0 // most extensions end at once
+	}
 	i := 0
+	for ; i+8 <= n; i += 8 {
+		if x := loadBytes(a, i) ^ loadBytes(b, i); x != 0 {
+			return i + bits.TrailingZeros64(x)/8
+		}
+	}
 	for i < n && a[i] == b[i] {
 		i++
 	}
@@ -125,7 +133,15 @@ func commonPrefixLenRunes(a, b []rune) int {
 }
 func commonPrefixLenString(a, b string) int {
 	n := min(len(a), len(b))
+	if n == 0 || a[0] != b[0] {
+		return 0 // most extensions end at once
+	}
 	i := 0
+	for ; i+8 <= n; i += 8 {
+		if x := loadString(a, i) ^ loadString(b, i); x != 0 {
+			return i + bits.TrailingZeros64(x)/8
+		}
+	}
 	for i < n && a[i] == b[i] {
 		i++
 	}
@@ -157,9 +173,20 @@ func commonPrefixLenAnySlices[T1, T2 any, S1 ~[]T1, S2 ~[]T2, C EqualsComparer[T
 }
 
 // commonSuffixLen* returns the length of the common suffix of a[ai:aj] and b[bi:bj].
+//
+// The byte and string variants compare eight bytes at a time: the highest
+// set bit of the XOR of two words locates the last differing byte.
 func commonSuffixLenBytes(a, b []byte) int {
 	n := min(len(a), len(b))
+	if n == 0 || a[len(a)-1] != b[len(b)-1] {
+		return 0 // most extensions end at once
+	}
 	i := 0
+	for ; i+8 <= n; i += 8 {
+		if x := loadBytes(a, len(a)-i-8) ^ loadBytes(b, len(b)-i-8); x != 0 {
+			return i + bits.LeadingZeros64(x)/8
+		}
+	}
 	for i < n && a[len(a)-1-i] == b[len(b)-1-i] {
 		i++
 	}
@@ -175,7 +202,15 @@ func commonSuffixLenRunes(a, b []rune) int {
 }
 func commonSuffixLenString(a, b string) int {
 	n := min(len(a), len(b))
+	if n == 0 || a[len(a)-1] != b[len(b)-1] {
+		return 0 // most extensions end at once
+	}
 	i := 0
+	for ; i+8 <= n; i += 8 {
+		if x := loadString(a, len(a)-i-8) ^ loadString(b, len(b)-i-8); x != 0 {
+			return i + bits.LeadingZeros64(x)/8
+		}
+	}
 	for i < n && a[len(a)-1-i] == b[len(b)-1-i] {
 		i++
 	}
@@ -206,6 +241,20 @@ func commonSuffixLenAnySlices[T1, T2 any, S1 ~[]T1, S2 ~[]T2, C EqualsComparer[T
 	return i
 }
 
+// loadBytes and loadString return the eight bytes at offset i as a
+// little-endian word, so that earlier bytes are less significant on every
+// architecture. The compiler combines the byte loads into a single load.
+func loadBytes(b []byte, i int) uint64 {
+	b = b[i : i+8 : i+8]
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
+func loadString(s string, i int) uint64 {
+	s = s[i : i+8]
+	return uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+		uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+}
+
 func min(x, y int) int {
 	if x < y {
 		return x
@@ -0,0 +1,48 @@
+package lcs
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCommonLenWords(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 40; n++ {
+		for diff := 0; diff <= n; diff++ {
+			a := strings.Repeat("ab", n)[:n]
+			b := []byte(a)
+			if diff < n {
+				b[diff] = 'x'
+			}
+			// Trail b by a random extra byte to test texts of different lengths.
+			if rng.Intn(2) == 0 {
+				b = append(b, 'y')
+			}
+
+			want := diff
+			if got := commonPrefixLenString(a, string(b)); got != want {
+				t.Errorf("commonPrefixLenString(%q, %q) = %d, want %d", a, b, got, want)
+			}
+			if got := commonPrefixLenBytes([]byte(a), b); got != want {
+				t.Errorf("commonPrefixLenBytes(%q, %q) = %d, want %d", a, b, got, want)
+			}
+
+			ra, rb := reverse(a), reverse(string(b))
+			if got := commonSuffixLenString(ra, rb); got != want {
+				t.Errorf("commonSuffixLenString(%q, %q) = %d, want %d", ra, rb, got, want)
+			}
+			if got := commonSuffixLenBytes([]byte(ra), []byte(rb)); got != want {
+				t.Errorf("commonSuffixLenBytes(%q, %q) = %d, want %d", ra, rb, got, want)
+			}
+		}
+	}
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
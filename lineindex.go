@@ -0,0 +1,215 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pgavlin/text"
+	"github.com/pgavlin/text/utf8"
+)
+
+// A Span is a half-open range [Start, End) of byte offsets in a document.
+type Span struct {
+	Start, End int
+}
+
+// Len returns the number of bytes in the span.
+func (s Span) Len() int { return s.End - s.Start }
+
+// IsEmpty reports whether the span contains no bytes.
+func (s Span) IsEmpty() bool { return s.Start == s.End }
+
+// Contains reports whether offset lies within the span.
+func (s Span) Contains(offset int) bool { return s.Start <= offset && offset < s.End }
+
+// Overlaps reports whether the two spans have any bytes in common.
+func (s Span) Overlaps(t Span) bool { return s.Start < t.End && t.Start < s.End }
+
+func (s Span) String() string { return fmt.Sprintf("[%d,%d)", s.Start, s.End) }
+
+// Span returns the span of the region replaced by the edit.
+func (e Edit[S]) Span() Span { return Span{e.Start, e.End} }
+
+// Unit is the unit in which a LineIndex measures columns.
+type Unit int
+
+const (
+	// Bytes measures columns in bytes of UTF-8.
+	Bytes Unit = iota
+	// Runes measures columns in Unicode code points.
+	Runes
+	// UTF16 measures columns in UTF-16 code units, as used by the Language
+	// Server Protocol and by JavaScript.
+	UTF16
+)
+
+// A LineIndex records the line structure of a document so that byte offsets
+// can be converted to and from line and column positions without rescanning
+// the document. Lines are terminated by "\n" and numbered from zero.
+type LineIndex[S text.String] struct {
+	src    S
+	starts []int // byte offset of the start of each line
+	cr     bool  // whether "\r\n" and a lone "\r" also terminate lines
+}
+
+// NewLineIndex returns a LineIndex for src.
+func NewLineIndex[S text.String](src S) *LineIndex[S] {
+	starts := make([]int, 1, text.Count(src, "\n")+1)
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &LineIndex[S]{src: src, starts: starts}
+}
+
+// NewLineIndexCR is like NewLineIndex, but lines may also be terminated by
+// "\r\n" or a lone "\r", as in the Language Server Protocol. Positions
+// that would split a "\r\n" are errors.
+func NewLineIndexCR[S text.String](src S) *LineIndex[S] {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\r':
+			if i+1 < len(src) && src[i+1] == '\n' {
+				i++
+			}
+			starts = append(starts, i+1)
+		case '\n':
+			starts = append(starts, i+1)
+		}
+	}
+	return &LineIndex[S]{src: src, starts: starts, cr: true}
+}
+
+// Text returns the indexed document.
+func (x *LineIndex[S]) Text() S { return x.src }
+
+// LineCount returns the number of lines in the document. A final line that
+// lacks a newline is counted; an empty document has no lines.
+func (x *LineIndex[S]) LineCount() int {
+	if x.starts[len(x.starts)-1] == len(x.src) {
+		return len(x.starts) - 1
+	}
+	return len(x.starts)
+}
+
+// LineSpan returns the span of the given line, including its newline.
+func (x *LineIndex[S]) LineSpan(line int) Span {
+	end := len(x.src)
+	if line+1 < len(x.starts) {
+		end = x.starts[line+1]
+	}
+	return Span{x.starts[line], end}
+}
+
+// ContentSpan returns the span of the given line, excluding its newline.
+func (x *LineIndex[S]) ContentSpan(line int) Span {
+	span := x.LineSpan(line)
+	if line+1 < len(x.starts) {
+		span.End--
+		if x.cr && x.src[span.End] == '\n' && span.End > span.Start && x.src[span.End-1] == '\r' {
+			span.End--
+		}
+	}
+	return span
+}
+
+// Line returns the content of the given line, including its newline.
+func (x *LineIndex[S]) Line(line int) S {
+	span := x.LineSpan(line)
+	return x.src[span.Start:span.End]
+}
+
+// LineOf returns the line containing the given byte offset. An offset
+// equal to the length of a document that ends in a newline belongs to the
+// (empty) line that follows it, which is numbered LineCount().
+func (x *LineIndex[S]) LineOf(offset int) int {
+	return sort.SearchInts(x.starts, offset+1) - 1
+}
+
+// Lines returns the half-open range [start, end) of lines that contain
+// the bytes of span. An empty span touches no lines, so start == end.
+func (x *LineIndex[S]) Lines(span Span) (start, end int) {
+	start = x.LineOf(span.Start)
+	if span.IsEmpty() {
+		return start, start
+	}
+	return start, x.LineOf(span.End-1) + 1
+}
+
+// Position returns the zero-based line and column of the given byte offset.
+// It returns an error if the offset is out of range or, for units other
+// than Bytes, if it falls inside the encoding of a rune.
+func (x *LineIndex[S]) Position(offset int, unit Unit) (line, col int, err error) {
+	if offset < 0 || offset > len(x.src) {
+		return 0, 0, fmt.Errorf("offset %d out of range [0, %d]", offset, len(x.src))
+	}
+	if x.cr && 0 < offset && offset < len(x.src) && x.src[offset-1] == '\r' && x.src[offset] == '\n' {
+		return 0, 0, fmt.Errorf("offset %d splits a CRLF line ending", offset)
+	}
+	line = x.LineOf(offset)
+	start := x.starts[line]
+	if unit == Bytes {
+		return line, offset - start, nil
+	}
+	if splitsRune(x.src, offset) {
+		return 0, 0, fmt.Errorf("offset %d is inside a rune", offset)
+	}
+	for i := start; i < offset; {
+		r, size := utf8.DecodeRune(x.src[i:offset])
+		col += unitLen(r, unit)
+		i += size
+	}
+	return line, col, nil
+}
+
+// Offset returns the byte offset of the given zero-based line and column.
+// It returns an error if the position does not lie within the document.
+// The column may address any byte of the line, including its newline.
+func (x *LineIndex[S]) Offset(line, col int, unit Unit) (int, error) {
+	if line < 0 || line >= len(x.starts) {
+		return 0, fmt.Errorf("line %d out of range [0, %d)", line, len(x.starts))
+	}
+	span := x.ContentSpan(line)
+	if unit == Bytes {
+		if col < 0 || col > span.Len() {
+			return 0, fmt.Errorf("column %d out of range [0, %d] on line %d", col, span.Len(), line)
+		}
+		return span.Start + col, nil
+	}
+	i := span.Start
+	for n := col; n > 0; {
+		if i >= span.End {
+			return 0, fmt.Errorf("column %d beyond end of line %d", col, line)
+		}
+		r, size := utf8.DecodeRune(x.src[i:span.End])
+		if n -= unitLen(r, unit); n < 0 {
+			return 0, fmt.Errorf("column %d is inside a surrogate pair on line %d", col, line)
+		}
+		i += size
+	}
+	return i, nil
+}
+
+// unitLen returns the number of units needed to encode r.
+func unitLen(r rune, unit Unit) int {
+	if unit == UTF16 && r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// splitsRune reports whether offset falls inside the encoding of a valid
+// rune in s.
+func splitsRune[S text.String](s S, offset int) bool {
+	if offset == 0 || offset >= len(s) || utf8.RuneStart(s[offset]) {
+		return false
+	}
+	i := offset - 1
+	for i > 0 && offset-i < utf8.UTFMax-1 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	_, size := utf8.DecodeRune(s[i:])
+	return i+size > offset
+}
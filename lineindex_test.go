@@ -0,0 +1,134 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+)
+
+func TestLineIndex(t *testing.T) {
+	// "é" is 2 bytes and 1 UTF-16 unit; "𐐀" is 4 bytes and 2 UTF-16 units.
+	const src = "ab\né𐐀x\n\nlast"
+	x := diff.NewLineIndex(src)
+
+	if got := x.LineCount(); got != 4 {
+		t.Errorf("LineCount() = %d, want 4", got)
+	}
+	for i, want := range []string{"ab\n", "é𐐀x\n", "\n", "last"} {
+		if got := x.Line(i); got != want {
+			t.Errorf("Line(%d) = %q, want %q", i, got, want)
+		}
+	}
+
+	for _, test := range []struct {
+		offset    int
+		unit      diff.Unit
+		line, col int
+	}{
+		{0, diff.Bytes, 0, 0},
+		{2, diff.Bytes, 0, 2}, // the newline
+		{3, diff.Runes, 1, 0},
+		{9, diff.Bytes, 1, 6},
+		{9, diff.Runes, 1, 2},
+		{9, diff.UTF16, 1, 3},
+		{11, diff.UTF16, 2, 0},
+		{16, diff.Runes, 3, 4}, // EOF
+	} {
+		line, col, err := x.Position(test.offset, test.unit)
+		if err != nil {
+			t.Errorf("Position(%d, %v): %v", test.offset, test.unit, err)
+			continue
+		}
+		if line != test.line || col != test.col {
+			t.Errorf("Position(%d, %v) = %d:%d, want %d:%d", test.offset, test.unit, line, col, test.line, test.col)
+		}
+		offset, err := x.Offset(test.line, test.col, test.unit)
+		if err != nil {
+			t.Errorf("Offset(%d, %d, %v): %v", test.line, test.col, test.unit, err)
+		} else if offset != test.offset {
+			t.Errorf("Offset(%d, %d, %v) = %d, want %d", test.line, test.col, test.unit, offset, test.offset)
+		}
+	}
+
+	if _, _, err := x.Position(6, diff.UTF16); err == nil {
+		t.Errorf("Position inside a rune succeeded")
+	}
+	if _, err := x.Offset(1, 2, diff.UTF16); err == nil {
+		t.Errorf("Offset inside a surrogate pair succeeded")
+	}
+	if _, err := x.Offset(0, 4, diff.Bytes); err == nil {
+		t.Errorf("Offset beyond end of line succeeded")
+	}
+	if _, err := x.Offset(4, 0, diff.Bytes); err == nil {
+		t.Errorf("Offset beyond last line succeeded")
+	}
+
+	if start, end := x.Lines(diff.Span{Start: 1, End: 4}); start != 0 || end != 2 {
+		t.Errorf("Lines([1,4)) = %d, %d; want 0, 2", start, end)
+	}
+	if start, end := x.Lines(diff.Span{Start: 3, End: 3}); start != 1 || end != 1 {
+		t.Errorf("Lines([3,3)) = %d, %d; want 1, 1", start, end)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	s := diff.Span{Start: 2, End: 5}
+	if s.Len() != 3 || s.IsEmpty() || !s.Contains(2) || s.Contains(5) {
+		t.Errorf("unexpected properties of %v", s)
+	}
+	if !s.Overlaps(diff.Span{Start: 4, End: 9}) || s.Overlaps(diff.Span{Start: 5, End: 9}) {
+		t.Errorf("unexpected overlaps of %v", s)
+	}
+	if got := (diff.Edit[string]{Start: 1, End: 3}).Span(); got != (diff.Span{Start: 1, End: 3}) {
+		t.Errorf("Edit.Span() = %v", got)
+	}
+}
+
+func TestToUnifiedIndex(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		want, err := diff.ToUnified(difftest.FileA, difftest.FileB, tc.In, tc.Edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := diff.ToUnifiedIndex(difftest.FileA, difftest.FileB, diff.NewLineIndex(tc.In), tc.Edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: ToUnifiedIndex:\n%s\nToUnified:\n%s", tc.Name, got, want)
+		}
+	}
+}
+
+func TestLineIndexCR(t *testing.T) {
+	const src = "a\r\nb\rc\nd"
+	x := diff.NewLineIndexCR(src)
+	for i, want := range []string{"a\r\n", "b\r", "c\n", "d"} {
+		if got := x.Line(i); got != want {
+			t.Errorf("Line(%d) = %q, want %q", i, got, want)
+		}
+	}
+	for i, want := range []diff.Span{{Start: 0, End: 1}, {Start: 3, End: 4}, {Start: 5, End: 6}, {Start: 7, End: 8}} {
+		if got := x.ContentSpan(i); got != want {
+			t.Errorf("ContentSpan(%d) = %v, want %v", i, got, want)
+		}
+	}
+	if line, col, err := x.Position(4, diff.Bytes); err != nil || line != 1 || col != 1 {
+		t.Errorf("Position(4) = %d:%d, %v; want 1:1", line, col, err)
+	}
+	if _, _, err := x.Position(2, diff.Bytes); err == nil {
+		t.Errorf("Position inside a CRLF succeeded")
+	}
+	if offset, err := x.Offset(0, 1, diff.Bytes); err != nil || offset != 1 {
+		t.Errorf("Offset(0, 1) = %d, %v; want 1", offset, err)
+	}
+	if _, err := x.Offset(0, 2, diff.Bytes); err == nil {
+		t.Errorf("Offset beyond the end of a CRLF line succeeded")
+	}
+
+	// Without CR, "\r" is part of the content of a line.
+	if got, want := diff.NewLineIndex(src).ContentSpan(0), (diff.Span{Start: 0, End: 2}); got != want {
+		t.Errorf("ContentSpan(0) = %v, want %v", got, want)
+	}
+}
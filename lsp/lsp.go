@@ -0,0 +1,227 @@
+// Package lsp converts between the byte-offset edits of
+// "github.com/pgavlin/diff" and the line/character TextEdit and
+// WorkspaceEdit values of the Language Server Protocol.
+package lsp
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/text"
+)
+
+// The types below mirror the shapes (and JSON encodings) of the
+// corresponding LSP protocol types.
+
+// DocumentURI is the URI of a text document.
+type DocumentURI string
+
+// PositionEncodingKind names the unit in which Position.Character is measured.
+type PositionEncodingKind string
+
+const (
+	// UTF8 counts characters in bytes of UTF-8.
+	UTF8 PositionEncodingKind = "utf-8"
+	// UTF16 counts characters in UTF-16 code units. This is the encoding
+	// that all clients must support.
+	UTF16 PositionEncodingKind = "utf-16"
+	// UTF32 counts characters in Unicode code points.
+	UTF32 PositionEncodingKind = "utf-32"
+)
+
+// Position is a zero-based line and character offset within a document.
+type Position struct {
+	Line      uint32 `json:"line"`
+	Character uint32 `json:"character"`
+}
+
+// Range is a half-open range of positions within a document.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextEdit is a replacement of a range of a document by new text.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// VersionedTextDocumentIdentifier identifies a specific version of a document.
+type VersionedTextDocumentIdentifier struct {
+	URI     DocumentURI `json:"uri"`
+	Version int32       `json:"version"`
+}
+
+// TextDocumentEdit is a set of edits to a single version of a document.
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// WorkspaceEdit is a set of edits to many documents.
+type WorkspaceEdit struct {
+	Changes         map[DocumentURI][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []TextDocumentEdit         `json:"documentChanges,omitempty"`
+}
+
+// ToTextEdits converts edits to src into LSP text edits whose characters
+// are measured in the given encoding. It returns an error if the edits are
+// inconsistent with src (see diff.Apply), if an edit boundary splits a CRLF
+// line ending, or, for the UTF16 and UTF32 encodings, if an edit boundary
+// falls inside a multi-byte character.
+func ToTextEdits[S text.String](src S, edits []diff.Edit[S], enc PositionEncodingKind) ([]TextEdit, error) {
+	edits, _, err := diff.Validate(len(src), edits)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMapper(src, enc)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]TextEdit, len(edits))
+	for i, edit := range edits {
+		start, err := m.position(edit.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := m.position(edit.End)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = TextEdit{Range: Range{Start: start, End: end}, NewText: string(edit.New)}
+	}
+	return res, nil
+}
+
+// FromTextEdits converts LSP text edits to src, whose characters are measured
+// in the given encoding, into byte-offset edits. As the protocol requires,
+// characters beyond the end of a line refer to the end of the line.
+func FromTextEdits[S text.String](src S, edits []TextEdit, enc PositionEncodingKind) ([]diff.Edit[S], error) {
+	m, err := newMapper(src, enc)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]diff.Edit[S], len(edits))
+	for i, edit := range edits {
+		start, err := m.offset(edit.Range.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := m.offset(edit.Range.End)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("edit %d: end %v precedes start %v", i, edit.Range.End, edit.Range.Start)
+		}
+		res[i] = diff.Edit[S]{Start: start, End: end, New: S(edit.NewText)}
+	}
+	return res, nil
+}
+
+// ToWorkspaceEdit converts the edits to each document in docs into a
+// WorkspaceEdit. Every document named in edits must be present in docs.
+func ToWorkspaceEdit[S text.String](docs map[DocumentURI]S, edits map[DocumentURI][]diff.Edit[S], enc PositionEncodingKind) (*WorkspaceEdit, error) {
+	w := &WorkspaceEdit{Changes: make(map[DocumentURI][]TextEdit, len(edits))}
+	for _, uri := range sortedURIs(edits) {
+		src, ok := docs[uri]
+		if !ok {
+			return nil, fmt.Errorf("no content for document %s", uri)
+		}
+		textEdits, err := ToTextEdits(src, edits[uri], enc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", uri, err)
+		}
+		w.Changes[uri] = textEdits
+	}
+	return w, nil
+}
+
+// FromWorkspaceEdit converts the edits in w into byte-offset edits to each
+// document in docs. Both Changes and DocumentChanges are honored; document
+// versions are not checked. Every document named in w must be present in docs.
+func FromWorkspaceEdit[S text.String](docs map[DocumentURI]S, w *WorkspaceEdit, enc PositionEncodingKind) (map[DocumentURI][]diff.Edit[S], error) {
+	res := make(map[DocumentURI][]diff.Edit[S])
+	add := func(uri DocumentURI, textEdits []TextEdit) error {
+		src, ok := docs[uri]
+		if !ok {
+			return fmt.Errorf("no content for document %s", uri)
+		}
+		edits, err := FromTextEdits(src, textEdits, enc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", uri, err)
+		}
+		res[uri] = append(res[uri], edits...)
+		return nil
+	}
+	for _, uri := range sortedURIs(w.Changes) {
+		if err := add(uri, w.Changes[uri]); err != nil {
+			return nil, err
+		}
+	}
+	for _, change := range w.DocumentChanges {
+		if err := add(change.TextDocument.URI, change.Edits); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func sortedURIs[T any](m map[DocumentURI]T) []DocumentURI {
+	uris := make([]DocumentURI, 0, len(m))
+	for uri := range m {
+		uris = append(uris, uri)
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	return uris
+}
+
+// A mapper converts between byte offsets and positions in a document.
+// Lines may be terminated by "\n", "\r\n" or "\r", as the protocol requires.
+type mapper[S text.String] struct {
+	index *diff.LineIndex[S]
+	unit  diff.Unit
+}
+
+func newMapper[S text.String](src S, enc PositionEncodingKind) (*mapper[S], error) {
+	var unit diff.Unit
+	switch enc {
+	case UTF8:
+		unit = diff.Bytes
+	case UTF16:
+		unit = diff.UTF16
+	case UTF32:
+		unit = diff.Runes
+	default:
+		return nil, fmt.Errorf("unsupported position encoding %q", enc)
+	}
+	return &mapper[S]{index: diff.NewLineIndexCR(src), unit: unit}, nil
+}
+
+func (m *mapper[S]) position(offset int) (Position, error) {
+	line, char, err := m.index.Position(offset, m.unit)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Line: uint32(line), Character: uint32(char)}, nil
+}
+
+func (m *mapper[S]) offset(pos Position) (int, error) {
+	line, lines := int(pos.Line), m.index.LineOf(len(m.index.Text()))+1
+	if line >= lines {
+		return 0, fmt.Errorf("line %d out of range [0, %d)", line, lines)
+	}
+
+	// Characters beyond the end of the line refer to its end.
+	end := m.index.ContentSpan(line).End
+	_, endChar, err := m.index.Position(end, m.unit)
+	if err != nil {
+		return 0, err
+	}
+	if int(pos.Character) >= endChar {
+		return end, nil
+	}
+	return m.index.Offset(line, int(pos.Character), m.unit)
+}
@@ -0,0 +1,165 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+	"github.com/pgavlin/diff/lsp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, enc := range []lsp.PositionEncodingKind{lsp.UTF8, lsp.UTF16, lsp.UTF32} {
+		for _, tc := range difftest.TestCases {
+			t.Run(string(enc)+"/"+tc.Name, func(t *testing.T) {
+				textEdits, err := lsp.ToTextEdits(tc.In, tc.Edits, enc)
+				if err != nil {
+					t.Fatalf("ToTextEdits: %v", err)
+				}
+				edits, err := lsp.FromTextEdits(tc.In, textEdits, enc)
+				if err != nil {
+					t.Fatalf("FromTextEdits: %v", err)
+				}
+				got, err := diff.Apply(tc.In, edits)
+				if err != nil {
+					t.Fatalf("Apply: %v", err)
+				}
+				if got != tc.Out {
+					t.Errorf("got %q, want %q", got, tc.Out)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	// "𐐀" is outside the BMP: 4 bytes, 2 UTF-16 units, 1 code point.
+	const src = "a\r\nxé𐐀y\rz\n"
+	edit := []diff.Edit[string]{{Start: 10, End: 11, New: "Y"}} // replace "y"
+
+	for _, test := range []struct {
+		enc  lsp.PositionEncodingKind
+		char uint32
+	}{
+		{lsp.UTF8, 7},
+		{lsp.UTF16, 4},
+		{lsp.UTF32, 3},
+	} {
+		got, err := lsp.ToTextEdits(src, edit, test.enc)
+		if err != nil {
+			t.Fatalf("%s: %v", test.enc, err)
+		}
+		want := []lsp.TextEdit{{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: test.char},
+				End:   lsp.Position{Line: 1, Character: test.char + 1},
+			},
+			NewText: "Y",
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", test.enc, got, want)
+		}
+	}
+
+	// A lone "\r" terminates a line.
+	got, err := lsp.ToTextEdits(src, []diff.Edit[string]{{Start: 12, End: 13, New: "Z"}}, lsp.UTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (lsp.Position{Line: 2, Character: 0}); got[0].Range.Start != want {
+		t.Errorf("got %+v, want %+v", got[0].Range.Start, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	const src = "a\r\n𐐀\n"
+	for _, test := range []struct {
+		name string
+		enc  lsp.PositionEncodingKind
+		edit diff.Edit[string]
+	}{
+		{"crlf", lsp.UTF16, diff.Edit[string]{Start: 2, End: 2, New: "x"}},
+		{"rune", lsp.UTF16, diff.Edit[string]{Start: 4, End: 4, New: "x"}},
+		{"encoding", "utf-7", diff.Edit[string]{Start: 0, End: 0, New: "x"}},
+		{"bounds", lsp.UTF8, diff.Edit[string]{Start: 0, End: 100, New: "x"}},
+	} {
+		if _, err := lsp.ToTextEdits(src, []diff.Edit[string]{test.edit}, test.enc); err == nil {
+			t.Errorf("%s: ToTextEdits succeeded unexpectedly", test.name)
+		}
+	}
+
+	surrogate := []lsp.TextEdit{{Range: lsp.Range{End: lsp.Position{Line: 1, Character: 1}}}}
+	if _, err := lsp.FromTextEdits(src, surrogate, lsp.UTF16); err == nil {
+		t.Errorf("FromTextEdits succeeded inside a surrogate pair")
+	}
+	line := []lsp.TextEdit{{Range: lsp.Range{End: lsp.Position{Line: 3}}}}
+	if _, err := lsp.FromTextEdits(src, line, lsp.UTF16); err == nil {
+		t.Errorf("FromTextEdits succeeded with an out-of-range line")
+	}
+}
+
+func TestClampCharacter(t *testing.T) {
+	const src = "ab\r\ncd"
+	edits, err := lsp.FromTextEdits(src, []lsp.TextEdit{{
+		Range: lsp.Range{
+			Start: lsp.Position{Line: 0, Character: 1},
+			End:   lsp.Position{Line: 0, Character: 99},
+		},
+		NewText: "X",
+	}}, lsp.UTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := diff.Apply(src, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "aX\r\ncd"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWorkspaceEdit(t *testing.T) {
+	docs := map[lsp.DocumentURI]string{
+		"file:///a.go": "package a\n",
+		"file:///b.go": "package b\n",
+	}
+	edits := map[lsp.DocumentURI][]diff.Edit[string]{
+		"file:///a.go": diff.Text(docs["file:///a.go"], "package aa\n"),
+		"file:///b.go": diff.Text(docs["file:///b.go"], "package c\n"),
+	}
+	w, err := lsp.ToWorkspaceEdit(docs, edits, lsp.UTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Round-trip through JSON, as a client would.
+	data, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded lsp.WorkspaceEdit
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := lsp.FromWorkspaceEdit(docs, &decoded, lsp.UTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for uri, want := range map[lsp.DocumentURI]string{"file:///a.go": "package aa\n", "file:///b.go": "package c\n"} {
+		out, err := diff.Apply(docs[uri], got[uri])
+		if err != nil {
+			t.Fatalf("%s: %v", uri, err)
+		}
+		if out != want {
+			t.Errorf("%s: got %q, want %q", uri, out, want)
+		}
+	}
+
+	if _, err := lsp.ToWorkspaceEdit(map[lsp.DocumentURI]string{}, edits, lsp.UTF16); err == nil {
+		t.Errorf("ToWorkspaceEdit succeeded without document content")
+	}
+}
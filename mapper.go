@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"sort"
+
+	"github.com/pgavlin/text"
+)
+
+// Bias selects where a Mapper places a position that lies inside a replaced
+// region, or at the point of an insertion.
+type Bias int
+
+const (
+	// BiasLeft places the position at the start of the replacement text.
+	BiasLeft Bias = iota
+	// BiasRight places the position at the end of the replacement text.
+	BiasRight
+)
+
+// A Mapper maps byte offsets and lines of a document to their positions in
+// the result of applying a set of edits to it, for example to carry cursor
+// positions or diagnostics from an old version of a file to a new one.
+// Each query takes O(log n) time in the number of edits.
+type Mapper struct {
+	offsets []region // replaced byte ranges, in order
+	lines   []region // replaced line ranges, in order
+}
+
+// A region is a half-open range [start, end) of the old document that is
+// replaced by the range [newStart, newEnd) of the new document.
+type region struct {
+	start, end       int
+	newStart, newEnd int
+}
+
+// NewMapper returns a Mapper from src to the result of applying edits to
+// it. It returns an error if the edits are inconsistent; see Apply.
+func NewMapper[S text.String](src S, edits []Edit[S]) (*Mapper, error) {
+	edits, _, err := Validate(len(src), edits)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mapper{}
+	delta := 0
+	for _, edit := range edits {
+		newStart := edit.Start + delta
+		m.offsets = addRegion(m.offsets, region{edit.Start, edit.End, newStart, newStart + len(edit.New)})
+		delta += len(edit.New) - (edit.End - edit.Start)
+	}
+
+	m.lines = lineRegions(src, edits)
+	return m, nil
+}
+
+// lineRegions returns the ranges of lines of src that are changed by
+// edits. Unlike lineEdits, it expands edits only as far as necessary, so a
+// line is considered changed only if an edit touches its content or its
+// newline.
+func lineRegions[S text.String](src S, edits []Edit[S]) []region {
+	edits, err := alignEdits(src, edits)
+	if err != nil {
+		panic(err) // the edits were validated by NewMapper
+	}
+
+	var regions []region
+	line, pos, delta := 0, 0, 0
+	for _, edit := range edits {
+		line += text.Count(src[pos:edit.Start], "\n")
+		pos = edit.Start
+
+		oldLines, newLines := countLines(src[edit.Start:edit.End]), countLines(edit.New)
+		regions = addRegion(regions, region{line, line + oldLines, line + delta, line + delta + newLines})
+		delta += newLines - oldLines
+	}
+	return regions
+}
+
+// addRegion appends r to regions, coalescing it with the last region if the
+// two are adjacent.
+func addRegion(regions []region, r region) []region {
+	if n := len(regions); n > 0 && regions[n-1].end == r.start {
+		regions[n-1].end, regions[n-1].newEnd = r.end, r.newEnd
+		return regions
+	}
+	return append(regions, r)
+}
+
+// countLines returns the number of lines in s, counting a final line that
+// lacks a newline.
+func countLines[S text.String](s S) int {
+	n := text.Count(s, "\n")
+	if len(s) > 0 && s[len(s)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// Offset returns the position in the new document of the given byte offset
+// of the old document.
+//
+// Offsets at the boundaries of a replaced region map to the corresponding
+// boundary of its replacement. Offsets strictly inside a replaced region no
+// longer exist: for these, Offset reports false, and places the result at
+// the start or end of the replacement according to bias. At the point of a
+// pure insertion, bias selects whether the offset lands before or after the
+// inserted text.
+func (m *Mapper) Offset(offset int, bias Bias) (int, bool) {
+	i := sort.Search(len(m.offsets), func(i int) bool { return m.offsets[i].end >= offset })
+	if i == len(m.offsets) || offset < m.offsets[i].start {
+		return offset + shift(m.offsets, i), true
+	}
+	r := m.offsets[i]
+	switch {
+	case r.start == r.end:
+		return r.biased(bias), true
+	case offset == r.start:
+		return r.newStart, true
+	case offset == r.end:
+		return r.newEnd, true
+	default:
+		return r.biased(bias), false
+	}
+}
+
+// Line returns the zero-based line number in the new document of the given
+// zero-based line of the old document.
+//
+// Lines that were changed in any way lie inside a replaced region: for these,
+// Line reports false, and returns the first (BiasLeft) or last (BiasRight)
+// line of the replacement. If the replacement is empty, both biases return
+// the line that follows it.
+func (m *Mapper) Line(line int, bias Bias) (int, bool) {
+	i := sort.Search(len(m.lines), func(i int) bool { return m.lines[i].end > line })
+	if i == len(m.lines) || line < m.lines[i].start {
+		return line + shift(m.lines, i), true
+	}
+	r := m.lines[i]
+	if bias == BiasRight && r.newEnd > r.newStart {
+		return r.newEnd - 1, false
+	}
+	return r.newStart, false
+}
+
+// Reverse returns a Mapper that maps positions in the new document back to
+// the old one.
+func (m *Mapper) Reverse() *Mapper {
+	return &Mapper{offsets: reverseRegions(m.offsets), lines: reverseRegions(m.lines)}
+}
+
+func reverseRegions(regions []region) []region {
+	res := make([]region, len(regions))
+	for i, r := range regions {
+		res[i] = region{r.newStart, r.newEnd, r.start, r.end}
+	}
+	return res
+}
+
+// shift returns the displacement of positions that precede regions[i],
+// or follow the last region if i == len(regions).
+func shift(regions []region, i int) int {
+	if i < len(regions) {
+		return regions[i].newStart - regions[i].start
+	}
+	if i > 0 {
+		return regions[i-1].newEnd - regions[i-1].end
+	}
+	return 0
+}
+
+func (r region) biased(bias Bias) int {
+	if bias == BiasRight {
+		return r.newEnd
+	}
+	return r.newStart
+}
@@ -0,0 +1,136 @@
+package diff_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff"
+)
+
+func TestMapperOffset(t *testing.T) {
+	// "hello world" -> "hello, brave new world!"
+	src := "hello world"
+	edits := []diff.Edit[string]{
+		{Start: 5, End: 6, New: ", brave new "},
+		{Start: 11, End: 11, New: "!"},
+	}
+	m, err := diff.NewMapper(src, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		offset int
+		bias   diff.Bias
+		want   int
+		ok     bool
+	}{
+		{0, diff.BiasLeft, 0, true},
+		{5, diff.BiasRight, 5, true},   // start of replaced region
+		{6, diff.BiasLeft, 17, true},   // end of replaced region
+		{8, diff.BiasLeft, 19, true},   // unchanged text shifts
+		{11, diff.BiasLeft, 22, true},  // before insertion
+		{11, diff.BiasRight, 23, true}, // after insertion
+	} {
+		got, ok := m.Offset(test.offset, test.bias)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Offset(%d, %v) = %d, %v; want %d, %v", test.offset, test.bias, got, ok, test.want, test.ok)
+		}
+	}
+
+	// A deleted position.
+	m, err = diff.NewMapper("abcdef", []diff.Edit[string]{{Start: 1, End: 4, New: "XY"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m.Offset(2, diff.BiasLeft); got != 1 || ok {
+		t.Errorf("Offset(2, BiasLeft) = %d, %v; want 1, false", got, ok)
+	}
+	if got, ok := m.Offset(2, diff.BiasRight); got != 3 || ok {
+		t.Errorf("Offset(2, BiasRight) = %d, %v; want 3, false", got, ok)
+	}
+	if got, ok := m.Reverse().Offset(5, diff.BiasLeft); got != 6 || !ok {
+		t.Errorf("Reverse().Offset(5, BiasLeft) = %d, %v; want 6, true", got, ok)
+	}
+}
+
+func TestMapperLine(t *testing.T) {
+	src := "a\nb\nc\nd\ne\n"
+	dst := "a\nB1\nB2\nc\ne\nf\n"
+	m, err := diff.NewMapper(src, diff.Lines(src, dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		line int
+		bias diff.Bias
+		want int
+		ok   bool
+	}{
+		{0, diff.BiasLeft, 0, true},
+		{1, diff.BiasLeft, 1, false}, // "b" replaced by two lines
+		{1, diff.BiasRight, 2, false},
+		{2, diff.BiasLeft, 3, true},
+		{3, diff.BiasLeft, 4, false}, // "d" deleted
+		{3, diff.BiasRight, 4, false},
+		{4, diff.BiasLeft, 4, true},
+	} {
+		got, ok := m.Line(test.line, test.bias)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Line(%d, %v) = %d, %v; want %d, %v", test.line, test.bias, got, ok, test.want, test.ok)
+		}
+	}
+	if got, ok := m.Reverse().Line(3, diff.BiasLeft); got != 2 || !ok {
+		t.Errorf("Reverse().Line(3) = %d, %v; want 2, true", got, ok)
+	}
+}
+
+func TestMapperRandom(t *testing.T) {
+	rand.Seed(1)
+	for i := 0; i < 1000; i++ {
+		a := randstr("ab\n", 32)
+		b := randstr("abc\n", 32)
+		edits := diff.Text(a, b)
+		m, err := diff.NewMapper(a, edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := m.Reverse()
+
+		// Every byte outside the edits survives at its mapped position.
+		deleted := make([]bool, len(a))
+		for _, e := range edits {
+			for j := e.Start; j < e.End; j++ {
+				deleted[j] = true
+			}
+		}
+		for j := 0; j < len(a); j++ {
+			if deleted[j] {
+				continue
+			}
+			got, ok := m.Offset(j, diff.BiasRight)
+			if !ok || b[got] != a[j] {
+				t.Fatalf("%q -> %q: Offset(%d) = %d, %v", a, b, j, got, ok)
+			}
+			if back, ok := r.Offset(got, diff.BiasRight); !ok || back != j {
+				t.Fatalf("%q -> %q: Reverse().Offset(%d) = %d, %v; want %d", a, b, got, back, ok, j)
+			}
+		}
+
+		// Every unchanged line survives at its mapped line.
+		aLines, bLines := splitLines(a), splitLines(b)
+		for j := range aLines {
+			if got, ok := m.Line(j, diff.BiasLeft); ok && (got >= len(bLines) || bLines[got] != aLines[j]) {
+				t.Fatalf("%q -> %q: Line(%d) = %d", a, b, j, got)
+			}
+		}
+	}
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
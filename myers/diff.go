@@ -6,6 +6,8 @@
 package myers
 
 import (
+	"context"
+
 	"github.com/pgavlin/diff"
 	"github.com/pgavlin/text"
 )
@@ -21,8 +23,30 @@ type Operation struct {
 }
 
 func ComputeEdits[S1, S2 text.String](before S1, after S2) []diff.Edit[S2] {
+	edits, _ := computeEdits(before, after, nil)
+	return edits
+}
+
+// ComputeEditsContext is like ComputeEdits, but returns ctx.Err() if ctx
+// is done before the edits are computed. The search takes time and memory
+// quadratic in the number of edits; see diff.LinesContext for a diff that
+// also limits them.
+func ComputeEditsContext[S1, S2 text.String](ctx context.Context, before S1, after S2) ([]diff.Edit[S2], error) {
+	edits, ok := computeEdits(before, after, func() bool { return ctx.Err() != nil })
+	if !ok {
+		return nil, ctx.Err()
+	}
+	return edits, nil
+}
+
+// computeEdits computes the edits of ComputeEdits, unless stop, if not nil,
+// returns true before they are computed.
+func computeEdits[S1, S2 text.String](before S1, after S2, stop func() bool) ([]diff.Edit[S2], bool) {
 	beforeLines, afterLines := splitLines(before), splitLines(after)
-	ops := Operations(beforeLines, afterLines)
+	ops, ok := operations(len(beforeLines), len(afterLines), func(x, y int) bool { return text.Equal(beforeLines[x], afterLines[y]) }, stop)
+	if !ok {
+		return nil, false
+	}
 
 	// Build a table mapping line number to offset.
 	lineOffsets := make([]int, 0, len(beforeLines)+1)
@@ -47,23 +71,65 @@ func ComputeEdits[S1, S2 text.String](before S1, after S2) []diff.Edit[S2] {
 			}
 		}
 	}
-	return edits
+	return edits, true
 }
 
 // Operations returns the list of operations to convert a into b, consolidating
 // operations for multiple lines and not including equal lines.
 func Operations[S1, S2 text.String, A ~[]S1, B ~[]S2](a A, b B) []Operation {
-	if len(a) == 0 && len(b) == 0 {
-		return nil
+	ops, _ := operations(len(a), len(b), func(x, y int) bool { return text.Equal(a[x], b[y]) }, nil)
+	return ops
+}
+
+// AllOperations returns the complete list of operations to convert a into b,
+// consolidating operations for multiple elements. Unlike Operations, runs of
+// equal elements are included as Equal operations, so the result covers every
+// index of both a and b in order. Every operation carries indices into both
+// sequences: for a Delete, ReplStart == ReplEnd, and for an Insert,
+// Start == End.
+func AllOperations[T comparable, A ~[]T, B ~[]T](a A, b B) []Operation {
+	ops, _ := operations(len(a), len(b), func(x, y int) bool { return a[x] == b[y] }, nil)
+
+	all := make([]Operation, 0, 2*len(ops)+1)
+	x, y := 0, 0
+	equal := func(end int) {
+		if x < end {
+			n := end - x
+			all = append(all, Operation{Kind: diff.Equal, Start: x, End: end, ReplStart: y, ReplEnd: y + n})
+			x, y = end, y+n
+		}
+	}
+	for _, op := range ops {
+		equal(op.Start)
+		switch op.Kind {
+		case diff.Delete:
+			all = append(all, Operation{Kind: diff.Delete, Start: op.Start, End: op.End, ReplStart: y, ReplEnd: y})
+			x = op.End
+		case diff.Insert:
+			all = append(all, Operation{Kind: diff.Insert, Start: x, End: x, ReplStart: y, ReplEnd: op.ReplEnd})
+			y = op.ReplEnd
+		}
 	}
+	equal(len(a))
+	return all
+}
 
-	trace, offset := shortestEditSequence(a, b)
-	snakes := backtrack(trace, len(a), len(b), offset)
+// operations computes the edit operations between sequences of lengths M
+// and N whose elements are compared by eq. It reports false if stop, if not
+// nil, returns true before they are computed.
+func operations(M, N int, eq func(x, y int) bool, stop func() bool) ([]Operation, bool) {
+	if M == 0 && N == 0 {
+		return nil, true
+	}
 
-	M, N := len(a), len(b)
+	trace, offset, ok := shortestEditSequence(M, N, eq, stop)
+	if !ok {
+		return nil, false
+	}
+	snakes := backtrack(trace, M, N, offset)
 
 	var i int
-	solution := make([]Operation, len(a)+len(b))
+	solution := make([]Operation, M+N)
 
 	add := func(op *Operation, i2, j2 int) {
 		if op == nil {
@@ -120,7 +186,7 @@ func Operations[S1, S2 text.String, A ~[]S1, B ~[]S2](a A, b B) []Operation {
 			break
 		}
 	}
-	return solution[:i]
+	return solution[:i], true
 }
 
 // backtrack uses the trace for the edit sequence computation and returns the
@@ -155,15 +221,21 @@ func backtrack(trace [][]int, x, y, offset int) [][]int {
 	return snakes
 }
 
-// shortestEditSequence returns the shortest edit sequence that converts a into b.
-func shortestEditSequence[S1, S2 text.String](a []S1, b []S2) ([][]int, int) {
-	M, N := len(a), len(b)
+// shortestEditSequence returns the shortest edit sequence that converts a
+// sequence of length M into a sequence of length N, where eq(x, y) reports
+// whether a[x] is equal to b[y]. If stop is not nil, it is called before
+// each step of the search, and if it returns true the search ends and
+// shortestEditSequence reports false.
+func shortestEditSequence(M, N int, eq func(x, y int) bool, stop func() bool) ([][]int, int, bool) {
 	V := make([]int, 2*(N+M)+1)
 	offset := N + M
 	trace := make([][]int, N+M+1)
 
 	// Iterate through the maximum possible length of the SES (N+M).
 	for d := 0; d <= N+M; d++ {
+		if stop != nil && stop() {
+			return nil, 0, false
+		}
 		copyV := make([]int, len(V))
 		// k lines are represented by the equation y = x - k. We move in
 		// increments of 2 because end points for even d are on even k lines.
@@ -181,7 +253,7 @@ func shortestEditSequence[S1, S2 text.String](a []S1, b []S2) ([][]int, int) {
 			y := x - k
 
 			// Diagonal moves while we have equal contents.
-			for x < M && y < N && text.Equal(a[x], b[y]) {
+			for x < M && y < N && eq(x, y) {
 				x++
 				y++
 			}
@@ -193,7 +265,7 @@ func shortestEditSequence[S1, S2 text.String](a []S1, b []S2) ([][]int, int) {
 				// Makes sure to save the state of the array before returning.
 				copy(copyV, V)
 				trace[d] = copyV
-				return trace, offset
+				return trace, offset, true
 			}
 		}
 
@@ -201,7 +273,7 @@ func shortestEditSequence[S1, S2 text.String](a []S1, b []S2) ([][]int, int) {
 		copy(copyV, V)
 		trace[d] = copyV
 	}
-	return nil, 0
+	return nil, 0, true
 }
 
 func splitLines[S text.String](t S) []S {
@@ -5,8 +5,13 @@
 package myers_test
 
 import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
 	"testing"
 
+	"github.com/pgavlin/diff"
 	"github.com/pgavlin/diff/difftest"
 	"github.com/pgavlin/diff/myers"
 )
@@ -14,3 +19,118 @@ import (
 func TestDiff(t *testing.T) {
 	difftest.DiffTest(t, myers.ComputeEdits[string, string])
 }
+
+func TestPatch(t *testing.T) {
+	difftest.VerifyPatch(t, myers.ComputeEdits[string, string])
+}
+
+// $ go test -fuzz=FuzzDiff ./myers
+func FuzzDiff(f *testing.F) {
+	difftest.FuzzAlgorithm(f, myers.ComputeEdits[string, string])
+}
+
+// countdown is a context that is canceled after its Err method has been
+// called n times.
+type countdown struct {
+	context.Context
+	n int
+}
+
+func (c *countdown) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestComputeEditsContext(t *testing.T) {
+	rand.Seed(2)
+	before, after := randstr("ab\n", 2000), randstr("ab\n", 2000)
+	edits, err := myers.ComputeEditsContext(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := myers.ComputeEdits(before, after); !reflect.DeepEqual(edits, want) {
+		t.Errorf("got edits %v, want %v", edits, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := myers.ComputeEditsContext(ctx, before, after); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: got error %v", err)
+	}
+	if _, err := myers.ComputeEditsContext(&countdown{context.Background(), 10}, before, after); !errors.Is(err, context.Canceled) {
+		t.Errorf("context canceled during the search: got error %v", err)
+	}
+}
+
+func TestAllOperations(t *testing.T) {
+	rand.Seed(1)
+	for i := 0; i < 1000; i++ {
+		a := []byte(randstr("abc", rand.Intn(16)))
+		b := []byte(randstr("abcd", rand.Intn(16)))
+
+		ops := myers.AllOperations(a, b)
+
+		// The operations must tile both sequences in order, equal runs must
+		// be equal, and replaying them must reconstruct b.
+		var got []byte
+		x, y := 0, 0
+		for j, op := range ops {
+			if op.Start != x || op.ReplStart != y {
+				t.Fatalf("AllOperations(%q, %q): op %d = %+v does not start at (%d, %d)", a, b, j, op, x, y)
+			}
+			switch op.Kind {
+			case diff.Equal:
+				if string(a[op.Start:op.End]) != string(b[op.ReplStart:op.ReplEnd]) {
+					t.Fatalf("AllOperations(%q, %q): op %d = %+v is not equal", a, b, j, op)
+				}
+				got = append(got, a[op.Start:op.End]...)
+			case diff.Delete:
+				if op.ReplStart != op.ReplEnd {
+					t.Fatalf("AllOperations(%q, %q): delete op %d = %+v has replacement", a, b, j, op)
+				}
+			case diff.Insert:
+				if op.Start != op.End {
+					t.Fatalf("AllOperations(%q, %q): insert op %d = %+v deletes", a, b, j, op)
+				}
+				got = append(got, b[op.ReplStart:op.ReplEnd]...)
+			}
+			if j > 0 && op.Kind == diff.Equal && ops[j-1].Kind == diff.Equal {
+				t.Fatalf("AllOperations(%q, %q): adjacent equal ops at %d", a, b, j)
+			}
+			x, y = op.End, op.ReplEnd
+		}
+		if x != len(a) || y != len(b) {
+			t.Fatalf("AllOperations(%q, %q): ops end at (%d, %d)", a, b, x, y)
+		}
+		if string(got) != string(b) {
+			t.Fatalf("AllOperations(%q, %q): got %q", a, b, got)
+		}
+	}
+}
+
+func TestAllOperationsLines(t *testing.T) {
+	a := []string{"a\n", "b\n", "c\n", "d\n"}
+	b := []string{"a\n", "c\n", "d\n", "e\n"}
+	want := []myers.Operation{
+		{Kind: diff.Equal, Start: 0, End: 1, ReplStart: 0, ReplEnd: 1},
+		{Kind: diff.Delete, Start: 1, End: 2, ReplStart: 1, ReplEnd: 1},
+		{Kind: diff.Equal, Start: 2, End: 4, ReplStart: 1, ReplEnd: 3},
+		{Kind: diff.Insert, Start: 4, End: 4, ReplStart: 3, ReplEnd: 4},
+	}
+	if got := myers.AllOperations(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllOperations:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+// return a random string of length n made of characters from s
+func randstr(s string, n int) string {
+	src := []rune(s)
+	x := make([]rune, n)
+	for i := 0; i < n; i++ {
+		x[i] = src[rand.Intn(len(src))]
+	}
+	return string(x)
+}
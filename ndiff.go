@@ -22,16 +22,33 @@ func Text[S1, S2 text.String](before S1, after S2) []Edit[S2] {
 func Lines[S1, S2 text.String](before S1, after S2) []Edit[S2] {
 	beforeLines, afterLines := splitLines(before), splitLines(after)
 
-	diffs := lcs.DiffLines(beforeLines, afterLines)
+	return lineDiffEdits(lcs.DiffLines(beforeLines, afterLines), beforeLines, afterLines, after)
+}
+
+// LinesParallel is like Lines, but diffs large texts on up to workers
+// goroutines at once. If workers is zero or negative, runtime.GOMAXPROCS(0)
+// is used. The edits transform before into after exactly, but may differ
+// from those of Lines; see lcs.DiffLinesParallel.
+func LinesParallel[S1, S2 text.String](before S1, after S2, workers int) []Edit[S2] {
+	beforeLines, afterLines := splitLines(before), splitLines(after)
+
+	return lineDiffEdits(lcs.DiffLinesParallel(beforeLines, afterLines, workers), beforeLines, afterLines, after)
+}
 
+// lineDiffEdits converts the diffs of the lines of two texts to edits.
+func lineDiffEdits[S1, S2 text.String](diffs []lcs.Diff, beforeLines []S1, afterLines []S2, after S2) []Edit[S2] {
 	// Build tables mapping line number to offset.
-	beforeLineOffsets, afterLineOffsets := lineOffsets(beforeLines), lineOffsets(afterLines)
+	return offsetDiffEdits(diffs, lineOffsets(beforeLines), lineOffsets(afterLines), after)
+}
 
-	edits := make([]Edit[S2], 0, len(diffs))
+// offsetDiffEdits converts the diffs of the lines of two texts to edits,
+// given tables mapping line number to offset.
+func offsetDiffEdits[S text.String](diffs []lcs.Diff, beforeLineOffsets, afterLineOffsets []int, after S) []Edit[S] {
+	edits := make([]Edit[S], 0, len(diffs))
 	for _, diff := range diffs {
 		start, end := beforeLineOffsets[diff.Start], beforeLineOffsets[diff.End]
 		replStart, replEnd := afterLineOffsets[diff.ReplStart], afterLineOffsets[diff.ReplEnd]
-		edits = append(edits, Edit[S2]{Start: start, End: end, New: after[replStart:replEnd]})
+		edits = append(edits, Edit[S]{Start: start, End: end, New: after[replStart:replEnd]})
 	}
 	return edits
 }
@@ -54,19 +71,25 @@ func diffText[S1, S2 text.String](before S1, after S2, binary bool) []Edit[S2] {
 }
 
 func diffASCII[S1, S2 text.String](before S1, after S2) []Edit[S2] {
-	diffs := lcs.DiffText(before, after)
+	return asciiEdits(lcs.DiffText(before, after), after)
+}
 
+// asciiEdits converts the diffs of the bytes of two texts to edits.
+func asciiEdits[S text.String](diffs []lcs.Diff, after S) []Edit[S] {
 	// Convert from LCS diffs.
-	res := make([]Edit[S2], len(diffs))
+	res := make([]Edit[S], len(diffs))
 	for i, d := range diffs {
-		res[i] = Edit[S2]{d.Start, d.End, after[d.ReplStart:d.ReplEnd]}
+		res[i] = Edit[S]{d.Start, d.End, after[d.ReplStart:d.ReplEnd]}
 	}
 	return res
 }
 
 func diffRunes[S text.String](before, after []rune) []Edit[S] {
-	diffs := lcs.DiffRunes(before, after)
+	return runeEdits[S](lcs.DiffRunes(before, after), before, after)
+}
 
+// runeEdits converts the diffs of the runes of two texts to edits.
+func runeEdits[S text.String](diffs []lcs.Diff, before, after []rune) []Edit[S] {
 	// The diffs returned by the lcs package use indexes
 	// into whatever slice was passed in.
 	// Convert rune offsets to byte offsets.
@@ -0,0 +1,195 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pgavlin/text"
+)
+
+// A FileStat summarizes the changes to one file as counts of inserted and
+// deleted lines, as in the output of "git diff --numstat".
+type FileStat struct {
+	Name       string
+	Insertions int
+	Deletions  int
+}
+
+// Stat returns the numbers of lines inserted and deleted by the unified
+// diff that applies edits to content. It returns an error if the edits are
+// inconsistent; see ApplyEdits.
+func Stat[S text.String](name string, content S, edits []Edit[S]) (FileStat, error) {
+	return StatIndex(name, NewLineIndex(content), edits)
+}
+
+// StatIndex is like Stat, but takes a prebuilt index of the content.
+func StatIndex[S text.String](name string, index *LineIndex[S], edits []Edit[S]) (FileStat, error) {
+	u, err := toUnified(name, name, index, edits, DefaultContextLines)
+	if err != nil {
+		return FileStat{}, err
+	}
+	return u.stat(), nil
+}
+
+// stat counts the lines inserted and deleted by the hunks of u.
+func (u unified) stat() FileStat {
+	s := FileStat{Name: u.From}
+	for _, h := range u.Hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Insert:
+				s.Insertions++
+			case Delete:
+				s.Deletions++
+			}
+		}
+	}
+	return s
+}
+
+// TotalStat returns the number of files changed by stats, and their total
+// numbers of inserted and deleted lines.
+func TotalStat(stats []FileStat) (files, insertions, deletions int) {
+	for _, s := range stats {
+		files++
+		insertions += s.Insertions
+		deletions += s.Deletions
+	}
+	return files, insertions, deletions
+}
+
+// WriteNumStat writes one line per file of the form "insertions\tdeletions\tname",
+// as "git diff --numstat" does.
+func WriteNumStat(w io.Writer, stats []FileStat) error {
+	for _, s := range stats {
+		if _, err := fmt.Fprintf(w, "%d\t%d\t%s\n", s.Insertions, s.Deletions, s.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteShortStat writes a one-line summary of the totals of stats, as
+// "git diff --shortstat" does. It writes nothing if stats is empty.
+func WriteShortStat(w io.Writer, stats []FileStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	files, insertions, deletions := TotalStat(stats)
+	var b strings.Builder
+	fmt.Fprintf(&b, " %d %s changed", files, plural(files, "file", "files"))
+	if insertions != 0 || deletions == 0 {
+		fmt.Fprintf(&b, ", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions != 0 || insertions == 0 {
+		fmt.Fprintf(&b, ", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// DefaultStatWidth is the width used by WriteStat when none is given.
+const DefaultStatWidth = 80
+
+// WriteStat writes a histogram of stats followed by their totals, as
+// "git diff --stat" does. Each line shows a file name, its number of changed
+// lines, and a bar of '+' and '-' characters; the names and bars are
+// shortened as needed to fit in width columns. If width is not positive,
+// DefaultStatWidth is used.
+func WriteStat(w io.Writer, stats []FileStat, width int) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	if width <= 0 {
+		width = DefaultStatWidth
+	}
+
+	maxName, maxChange := 0, 0
+	for _, s := range stats {
+		if n := len(s.Name); n > maxName {
+			maxName = n
+		}
+		if n := s.Insertions + s.Deletions; n > maxChange {
+			maxChange = n
+		}
+	}
+	numberWidth := len(fmt.Sprint(maxChange))
+
+	// Allot the width as git does: the graph gets up to 3/8 of it, and the
+	// names get the rest, but at least 16+6 columns are always used.
+	if width < 16+6+numberWidth {
+		width = 16 + 6 + numberWidth
+	}
+	graphWidth, nameWidth := maxChange, maxName
+	if nameWidth+numberWidth+6+graphWidth > width {
+		if graphWidth > width*3/8-numberWidth-6 {
+			graphWidth = width*3/8 - numberWidth - 6
+			if graphWidth < 6 {
+				graphWidth = 6
+			}
+		}
+		if nameWidth > width-numberWidth-6-graphWidth {
+			nameWidth = width - numberWidth - 6 - graphWidth
+		} else {
+			graphWidth = width - numberWidth - 6 - nameWidth
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range stats {
+		name := s.Name
+		if len(name) > nameWidth {
+			// Keep the end of the name, preferably from a path separator.
+			name = name[len(name)-(nameWidth-3):]
+			if slash := strings.IndexByte(name, '/'); slash >= 0 {
+				name = name[slash:]
+			}
+			name = "..." + name
+		}
+
+		add, del := s.Insertions, s.Deletions
+		if graphWidth <= maxChange {
+			total := scaleLinear(add+del, graphWidth, maxChange)
+			if total < 2 && add != 0 && del != 0 {
+				total = 2
+			}
+			if add < del {
+				add = scaleLinear(add, graphWidth, maxChange)
+				del = total - add
+			} else {
+				del = scaleLinear(del, graphWidth, maxChange)
+				add = total - del
+			}
+		}
+
+		fmt.Fprintf(&b, " %-*s | %*d", nameWidth, name, numberWidth, s.Insertions+s.Deletions)
+		if add+del != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.Repeat("+", add))
+		b.WriteString(strings.Repeat("-", del))
+		b.WriteByte('\n')
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	return WriteShortStat(w, stats)
+}
+
+// scaleLinear scales n, which is at most max, to fit in width columns,
+// guaranteeing that a non-zero n is given at least one column.
+func scaleLinear(n, width, max int) int {
+	if n == 0 {
+		return 0
+	}
+	return 1 + n*(width-1)/max
+}
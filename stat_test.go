@@ -0,0 +1,120 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/difftest"
+)
+
+func TestStat(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		got, err := diff.Stat(difftest.FileA, tc.In, tc.Edits)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.Name, err)
+		}
+
+		// Count the lines of the unified diff by hand.
+		want := diff.FileStat{Name: difftest.FileA}
+		u, err := diff.ToUnified(difftest.FileA, difftest.FileB, tc.In, tc.Edits)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.Name, err)
+		}
+		for _, l := range strings.SplitAfter(u, "\n") {
+			switch {
+			case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+			case strings.HasPrefix(l, "+"):
+				want.Insertions++
+			case strings.HasPrefix(l, "-"):
+				want.Deletions++
+			}
+		}
+		if got != want {
+			t.Errorf("%s: Stat = %+v, want %+v", tc.Name, got, want)
+		}
+	}
+}
+
+// statFiles and the expected outputs below were checked against git.
+var statFiles = []diff.FileStat{
+	{Name: "big.txt", Insertions: 111, Deletions: 111},
+	{Name: "only_del.txt", Insertions: 0, Deletions: 3},
+	{Name: "small.txt", Insertions: 3, Deletions: 1},
+	{Name: "some/deeply/nested/directory/with_a_rather_long_file_name.go", Insertions: 1, Deletions: 0},
+}
+
+func TestWriteStat(t *testing.T) {
+	for _, test := range []struct {
+		width int
+		want  string
+	}{
+		{0, ` big.txt                                            | 222 ++++++++++-----------
+ only_del.txt                                       |   3 -
+ small.txt                                          |   4 +-
+ .../directory/with_a_rather_long_file_name.go      |   1 +
+ 4 files changed, 115 insertions(+), 115 deletions(-)
+`},
+		{40, ` big.txt                   | 222 +++---
+ only_del.txt              |   3 -
+ small.txt                 |   4 +-
+ ...ther_long_file_name.go |   1 +
+ 4 files changed, 115 insertions(+), 115 deletions(-)
+`},
+		{200, ` big.txt                                                      | 222 ` + strings.Repeat("+", 65) + strings.Repeat("-", 66) + `
+ only_del.txt                                                 |   3 --
+ small.txt                                                    |   4 ++-
+ some/deeply/nested/directory/with_a_rather_long_file_name.go |   1 +
+ 4 files changed, 115 insertions(+), 115 deletions(-)
+`},
+	} {
+		var b strings.Builder
+		if err := diff.WriteStat(&b, statFiles, test.width); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != test.want {
+			t.Errorf("WriteStat(width=%d):\ngot:\n%s\nwant:\n%s", test.width, got, test.want)
+		}
+	}
+
+	// Files with few changes are not scaled up.
+	var b strings.Builder
+	if err := diff.WriteStat(&b, []diff.FileStat{{Name: "a", Insertions: 2, Deletions: 1}, {Name: "b"}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := " a | 3 ++-\n b | 0\n 2 files changed, 2 insertions(+), 1 deletion(-)\n"; b.String() != want {
+		t.Errorf("WriteStat:\ngot:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestWriteNumStat(t *testing.T) {
+	var b strings.Builder
+	if err := diff.WriteNumStat(&b, statFiles); err != nil {
+		t.Fatal(err)
+	}
+	want := "111\t111\tbig.txt\n0\t3\tonly_del.txt\n3\t1\tsmall.txt\n1\t0\tsome/deeply/nested/directory/with_a_rather_long_file_name.go\n"
+	if b.String() != want {
+		t.Errorf("WriteNumStat:\ngot:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestWriteShortStat(t *testing.T) {
+	for _, test := range []struct {
+		stats []diff.FileStat
+		want  string
+	}{
+		{nil, ""},
+		{[]diff.FileStat{{Insertions: 1}}, " 1 file changed, 1 insertion(+)\n"},
+		{[]diff.FileStat{{Deletions: 2}, {}}, " 2 files changed, 2 deletions(-)\n"},
+		{[]diff.FileStat{{}}, " 1 file changed, 0 insertions(+), 0 deletions(-)\n"},
+		{statFiles, " 4 files changed, 115 insertions(+), 115 deletions(-)\n"},
+	} {
+		var b strings.Builder
+		if err := diff.WriteShortStat(&b, test.stats); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.want {
+			t.Errorf("WriteShortStat(%v) = %q, want %q", test.stats, b.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pgavlin/text"
+)
+
+// ChangeKind describes how an entry differs between two trees.
+type ChangeKind int
+
+const (
+	// Added is the kind of a file that is present only in the new tree.
+	Added ChangeKind = iota
+	// Removed is the kind of a file that is present only in the old tree.
+	Removed
+	// Modified is the kind of a file whose contents differ between the trees.
+	Modified
+	// TypeChanged is the kind of a path that is a file in one tree and a
+	// directory in the other.
+	TypeChanged
+)
+
+// String returns a human readable representation of a ChangeKind.
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	case TypeChanged:
+		return "type changed"
+	default:
+		return "unknown"
+	}
+}
+
+// A FileChange describes a difference between two trees.
+type FileChange struct {
+	// Path is the slash-separated path of the entry relative to the roots.
+	Path string
+	// Kind is the kind of the change.
+	Kind ChangeKind
+	// Old and New are the contents of the file in the old and new trees. A
+	// side on which the path is missing or is a directory is empty.
+	Old, New string
+	// OldIsDir and NewIsDir report which side of a TypeChanged entry is a
+	// directory.
+	OldIsDir, NewIsDir bool
+	// Binary reports whether either side appears to be binary, in which case
+	// Edits is nil.
+	Binary bool
+	// Edits transform Old into New.
+	Edits []Edit[string]
+}
+
+// TreeOptions controls how DiffFS compares two trees.
+type TreeOptions struct {
+	// Include, if non-empty, restricts the comparison to files that match at
+	// least one of these patterns. A pattern uses the syntax of path.Match,
+	// and matches a path if it matches either the whole slash-separated path
+	// or its last element.
+	Include []string
+	// Exclude lists patterns for files and directories to skip.
+	Exclude []string
+	// Compute computes the edits between the two versions of a file. If nil,
+	// Lines is used.
+	Compute func(before, after string) []Edit[string]
+}
+
+// matchAny reports whether p matches any of patterns. A pattern matches a
+// path if it matches the whole path or its last element, using the syntax
+// of path.Match.
+func matchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffFS compares the trees a and b and returns their differences, ordered
+// by path. Files are paired by path; files whose contents are identical are
+// skipped without computing a diff. Regular files of different sizes are
+// known to differ, and files that are the same file in both trees (see
+// os.SameFile) are known to be identical, without reading them. If a path
+// is a file in one tree and a directory in the other, DiffFS reports a
+// TypeChanged entry for the file and reports each file in the directory as
+// added or removed.
+//
+// Entries that are neither directories nor regular files are read as if they
+// were regular files.
+func DiffFS(a, b fs.FS, opts *TreeOptions) ([]FileChange, error) {
+	if opts == nil {
+		opts = &TreeOptions{}
+	}
+	for _, patterns := range [][]string{opts.Include, opts.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	t := &treeDiffer{a: a, b: b, opts: opts}
+	if err := t.walk("."); err != nil {
+		return nil, err
+	}
+	return t.changes, nil
+}
+
+type treeDiffer struct {
+	a, b    fs.FS
+	opts    *TreeOptions
+	changes []FileChange
+}
+
+// walk compares the directory dir of both trees. Either may be missing.
+func (t *treeDiffer) walk(dir string) error {
+	as, err := readDir(t.a, dir)
+	if err != nil {
+		return err
+	}
+	bs, err := readDir(t.b, dir)
+	if err != nil {
+		return err
+	}
+
+	// Merge the two sorted listings.
+	for len(as) > 0 || len(bs) > 0 {
+		var ae, be fs.DirEntry
+		switch {
+		case len(bs) == 0 || len(as) > 0 && as[0].Name() < bs[0].Name():
+			ae, as = as[0], as[1:]
+		case len(as) == 0 || bs[0].Name() < as[0].Name():
+			be, bs = bs[0], bs[1:]
+		default:
+			ae, be, as, bs = as[0], bs[0], as[1:], bs[1:]
+		}
+
+		var p string
+		if ae != nil {
+			p = path.Join(dir, ae.Name())
+		} else {
+			p = path.Join(dir, be.Name())
+		}
+		if matchAny(t.opts.Exclude, p) {
+			continue
+		}
+		if err := t.entry(p, ae, be); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entry compares the entries at path p, either of which may be nil.
+func (t *treeDiffer) entry(p string, ae, be fs.DirEntry) error {
+	aDir, bDir := ae != nil && ae.IsDir(), be != nil && be.IsDir()
+	if aDir || bDir {
+		if ae != nil && be != nil && aDir != bDir {
+			// Report the file side; the directory side is reported file by
+			// file below.
+			if err := t.file(p, TypeChanged, !aDir, !bDir); err != nil {
+				return err
+			}
+		}
+		return t.walk(p)
+	}
+
+	switch {
+	case be == nil:
+		return t.file(p, Removed, true, false)
+	case ae == nil:
+		return t.file(p, Added, false, true)
+	default:
+		return t.file(p, Modified, true, true)
+	}
+}
+
+// file records a change of the given kind to the file at path p, which is
+// read from the trees in which it is present.
+func (t *treeDiffer) file(p string, kind ChangeKind, inA, inB bool) error {
+	if len(t.opts.Include) != 0 && !matchAny(t.opts.Include, p) {
+		return nil
+	}
+	if kind == Modified {
+		same, err := t.unchanged(p)
+		if err != nil || same {
+			return err
+		}
+	}
+
+	var old, new []byte
+	if inA {
+		content, err := fs.ReadFile(t.a, p)
+		if err != nil {
+			return err
+		}
+		old = content
+	}
+	if inB {
+		content, err := fs.ReadFile(t.b, p)
+		if err != nil {
+			return err
+		}
+		new = content
+	}
+	c := FileChange{Path: p, Kind: kind, Old: string(old), New: string(new)}
+	if kind == TypeChanged {
+		c.OldIsDir, c.NewIsDir = !inA, !inB
+	}
+	if IsBinary(old) || IsBinary(new) {
+		c.Binary = true
+	} else {
+		compute := t.opts.Compute
+		if compute == nil {
+			compute = Lines[string, string]
+		}
+		c.Edits = compute(c.Old, c.New)
+	}
+	t.changes = append(t.changes, c)
+	return nil
+}
+
+// readDir returns the sorted entries of dir in fsys, or nil if dir does not
+// exist or is not a directory.
+func readDir(fsys fs.FS, dir string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if info, statErr := fs.Stat(fsys, dir); statErr == nil && !info.IsDir() {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entries, nil
+}
+
+// unchanged reports whether the file at path p has the same contents in
+// both trees. It compares their sizes and identities before their
+// contents, which it reads a block at a time.
+func (t *treeDiffer) unchanged(p string) (bool, error) {
+	ai, err := fs.Stat(t.a, p)
+	if err != nil {
+		return false, err
+	}
+	bi, err := fs.Stat(t.b, p)
+	if err != nil {
+		return false, err
+	}
+	if ai.Mode().IsRegular() && bi.Mode().IsRegular() && ai.Size() != bi.Size() {
+		return false, nil
+	}
+	if os.SameFile(ai, bi) {
+		return true, nil
+	}
+
+	af, err := t.a.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer af.Close()
+	bf, err := t.b.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer bf.Close()
+	abuf, bbuf := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		an, aerr := io.ReadFull(af, abuf)
+		bn, berr := io.ReadFull(bf, bbuf)
+		if !bytes.Equal(abuf[:an], bbuf[:bn]) {
+			return false, nil
+		}
+		aeof := aerr == io.EOF || aerr == io.ErrUnexpectedEOF
+		beof := berr == io.EOF || berr == io.ErrUnexpectedEOF
+		switch {
+		case aerr != nil && !aeof:
+			return false, aerr
+		case berr != nil && !beof:
+			return false, berr
+		case aeof || beof:
+			return aeof && beof, nil
+		}
+	}
+}
+
+// IsBinary reports whether content appears to be binary, using the same
+// heuristic as git and GNU diff: a NUL byte within the first 8000 bytes.
+func IsBinary[S text.String](content S) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return text.IndexByte(content, 0) >= 0
+}
+
+// ToUnifiedTree returns a multi-file unified diff of changes, in the style of
+// "diff -ruN". The old and new prefixes are prepended to the paths of the
+// old and new files, typically "a/" and "b/"; a file that is missing on one
+// side is named /dev/null on that side, as git does. Binary files are
+// reported with a one-line message.
+func ToUnifiedTree(changes []FileChange, oldPrefix, newPrefix string) (string, error) {
+	var b strings.Builder
+	for _, c := range changes {
+		oldName, newName := oldPrefix+c.Path, newPrefix+c.Path
+		switch {
+		case c.Kind == Added, c.OldIsDir:
+			oldName = "/dev/null"
+		case c.Kind == Removed, c.NewIsDir:
+			newName = "/dev/null"
+		}
+		if c.Binary {
+			fmt.Fprintf(&b, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+		u, err := ToUnified(oldName, newName, c.Old, c.Edits)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", c.Path, err)
+		}
+		b.WriteString(u)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,252 @@
+package diff_test
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pgavlin/diff"
+	"github.com/pgavlin/diff/testenv"
+)
+
+var (
+	treeA = fstest.MapFS{
+		"same.txt":          {Data: []byte("same\n")},
+		"mod.txt":           {Data: []byte("a\nb\nc\n")},
+		"gone.txt":          {Data: []byte("bye\n")},
+		"dir/nested.go":     {Data: []byte("package p\n")},
+		"typ":               {Data: []byte("file\n")},
+		"bin.dat":           {Data: []byte("x\x00y")},
+		"vendor/skip.go":    {Data: []byte("old\n")},
+		"notes.md":          {Data: []byte("old\n")},
+		"olddir/inside.txt": {Data: []byte("inside\n")},
+	}
+	treeB = fstest.MapFS{
+		"same.txt":          {Data: []byte("same\n")},
+		"mod.txt":           {Data: []byte("a\nB\nc\n")},
+		"new.txt":           {Data: []byte("hello\n")},
+		"dir/nested.go":     {Data: []byte("package q\n")},
+		"typ/child.txt":     {Data: []byte("child\n")},
+		"bin.dat":           {Data: []byte("x\x00z")},
+		"vendor/skip.go":    {Data: []byte("new\n")},
+		"notes.md":          {Data: []byte("new\n")},
+		"olddir/inside.txt": {Data: []byte("inside\n")},
+	}
+)
+
+func TestDiffFS(t *testing.T) {
+	changes, err := diff.DiffFS(treeA, treeB, &diff.TreeOptions{Exclude: []string{"vendor", "*.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type summary struct {
+		path   string
+		kind   diff.ChangeKind
+		binary bool
+	}
+	want := []summary{
+		{"bin.dat", diff.Modified, true},
+		{"dir/nested.go", diff.Modified, false},
+		{"gone.txt", diff.Removed, false},
+		{"mod.txt", diff.Modified, false},
+		{"new.txt", diff.Added, false},
+		{"typ", diff.TypeChanged, false},
+		{"typ/child.txt", diff.Added, false},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, c := range changes {
+		if got := (summary{c.Path, c.Kind, c.Binary}); got != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got, want[i])
+		}
+		if c.Binary {
+			continue
+		}
+		got, err := diff.Apply(c.Old, c.Edits)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Path, err)
+		}
+		if got != c.New {
+			t.Errorf("%s: Apply = %q, want %q", c.Path, got, c.New)
+		}
+	}
+	if typ := changes[5]; typ.OldIsDir || !typ.NewIsDir {
+		t.Errorf("typ: OldIsDir, NewIsDir = %v, %v", typ.OldIsDir, typ.NewIsDir)
+	}
+
+	changes, err = diff.DiffFS(treeA, treeB, &diff.TreeOptions{Include: []string{"*.go"}, Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "dir/nested.go" {
+		t.Errorf("Include *.go: got %+v", changes)
+	}
+
+	if _, err := diff.DiffFS(treeA, treeB, &diff.TreeOptions{Exclude: []string{"["}}); err == nil {
+		t.Errorf("bad pattern succeeded")
+	}
+}
+
+// countingFS counts the files opened in an fs.FS.
+type countingFS struct {
+	fs.FS
+	opened map[string]int
+}
+
+func (c countingFS) Open(name string) (fs.File, error) {
+	f, err := c.FS.Open(name)
+	if err == nil {
+		if info, err := f.Stat(); err == nil && !info.IsDir() {
+			c.opened[name]++
+		}
+	}
+	return f, err
+}
+
+func (c countingFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(c.FS, name)
+}
+
+func TestDiffFSUnchanged(t *testing.T) {
+	// A tree compared with itself reads no files.
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("text\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := countingFS{os.DirFS(dir), map[string]int{}}
+	b := countingFS{os.DirFS(dir), map[string]int{}}
+	changes, err := diff.DiffFS(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 || len(a.opened) != 0 || len(b.opened) != 0 {
+		t.Errorf("got %d changes, opened %v and %v", len(changes), a.opened, b.opened)
+	}
+
+	// Files of different sizes are read only to diff them, and identical
+	// files that are not the same file are read only to compare them.
+	a = countingFS{fstest.MapFS{
+		"size.txt": {Data: []byte("short\n")},
+		"same.txt": {Data: []byte("same\n")},
+	}, map[string]int{}}
+	b = countingFS{fstest.MapFS{
+		"size.txt": {Data: []byte("longer\n")},
+		"same.txt": {Data: []byte("same\n")},
+	}, map[string]int{}}
+	changes, err = diff.DiffFS(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "size.txt" {
+		t.Fatalf("got changes %+v", changes)
+	}
+	want := map[string]int{"size.txt": 1, "same.txt": 1}
+	if !reflect.DeepEqual(a.opened, want) || !reflect.DeepEqual(b.opened, want) {
+		t.Errorf("opened %v and %v, want %v", a.opened, b.opened, want)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		want    bool
+	}{
+		{"", false},
+		{"text\n", false},
+		{"x\x00y", true},
+		{strings.Repeat("x", 7999) + "\x00", true},
+		{strings.Repeat("x", 8000) + "\x00", false},
+	} {
+		if got := diff.IsBinary(tc.content); got != tc.want {
+			t.Errorf("IsBinary(%.10q... of %d bytes) = %v, want %v", tc.content, len(tc.content), got, tc.want)
+		}
+	}
+}
+
+func TestToUnifiedTree(t *testing.T) {
+	changes, err := diff.DiffFS(treeA, treeB, &diff.TreeOptions{Exclude: []string{"bin.dat", "typ"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := diff.ToUnifiedTree(changes, "a/", "b/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `--- a/dir/nested.go
++++ b/dir/nested.go
+@@ -1 +1 @@
+-package p
++package q
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+--- a/mod.txt
++++ b/mod.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+--- a/notes.md
++++ b/notes.md
+@@ -1 +1 @@
+-old
++new
+--- a/vendor/skip.go
++++ b/vendor/skip.go
+@@ -1 +1 @@
+-old
++new
+`
+	if got != want {
+		t.Errorf("ToUnifiedTree:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The combined diff applies to a copy of the old tree.
+	testenv.NeedsTool(t, "patch")
+	dir := t.TempDir()
+	for name, f := range treeA {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, f.Data, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	patch := filepath.Join(t.TempDir(), "tree.patch")
+	if err := os.WriteFile(patch, []byte(got), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("patch", "-p1", "-s", "-E", "-i", patch)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("patch: %v\n%s", err, out)
+	}
+	for _, name := range []string{"mod.txt", "new.txt", "dir/nested.go"} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(treeB[name].Data) {
+			t.Errorf("patched %s = %q, want %q", name, data, treeB[name].Data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gone.txt")); !os.IsNotExist(err) {
+		t.Errorf("gone.txt was not removed: %v", err)
+	}
+}
@@ -25,11 +25,31 @@ func Unified[S text.String](oldLabel, newLabel string, old, new S) string {
 	return unified
 }
 
+// DefaultContextLines is the number of unchanged lines that ToUnified shows
+// before and after each change.
+const DefaultContextLines = 3
+
 // ToUnified applies the edits to content and returns a unified diff.
 // The old and new labels are the names of the content and result files.
 // It returns an error if the edits are inconsistent; see ApplyEdits.
 func ToUnified[S text.String](oldLabel, newLabel string, content S, edits []Edit[S]) (string, error) {
-	u, err := toUnified(oldLabel, newLabel, content, edits)
+	return ToUnifiedContext(oldLabel, newLabel, content, edits, DefaultContextLines)
+}
+
+// ToUnifiedContext is like ToUnified, but shows contextLines unchanged
+// lines before and after each change, as "diff -U" does.
+func ToUnifiedContext[S text.String](oldLabel, newLabel string, content S, edits []Edit[S], contextLines int) (string, error) {
+	u, err := toUnified(oldLabel, newLabel, NewLineIndex(content), edits, contextLines)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// ToUnifiedIndex is like ToUnified, but takes a prebuilt index of the
+// content, which saves rescanning it when it is rendered more than once.
+func ToUnifiedIndex[S text.String](oldLabel, newLabel string, index *LineIndex[S], edits []Edit[S]) (string, error) {
+	u, err := toUnified(oldLabel, newLabel, index, edits, DefaultContextLines)
 	if err != nil {
 		return "", err
 	}
@@ -96,14 +116,14 @@ func (k OpKind) String() string {
 	}
 }
 
-const (
-	edge = 3
-	gap  = edge * 2
-)
-
-// toUnified takes a file contents and a sequence of edits, and calculates
-// a unified diff that represents those edits.
-func toUnified[S text.String](fromName, toName string, content S, edits []Edit[S]) (unified, error) {
+// toUnified takes an index of a file's contents and a sequence of edits,
+// and calculates a unified diff that represents those edits, with edge
+// lines of context around each change.
+func toUnified[S text.String](fromName, toName string, index *LineIndex[S], edits []Edit[S], edge int) (unified, error) {
+	if edge < 0 {
+		edge = 0
+	}
+	gap := edge * 2
 	u := unified{
 		From: fromName,
 		To:   toName,
@@ -111,20 +131,19 @@ func toUnified[S text.String](fromName, toName string, content S, edits []Edit[S
 	if len(edits) == 0 {
 		return u, nil
 	}
+	content := index.Text()
 	var err error
-	edits, err = lineEdits(content, edits) // expand to whole lines
+	edits, err = alignEdits(content, edits) // expand to whole lines
 	if err != nil {
 		return u, err
 	}
-	lines := splitLines(content)
 	var h *hunk
 	last := 0
 	toLine := 0
 	for _, edit := range edits {
 		// Compute the zero-based line numbers of the edit start and end.
-		// TODO(adonovan): opt: compute incrementally, avoid O(n^2).
-		start := text.Count(content[:edit.Start], "\n")
-		end := text.Count(content[:edit.End], "\n")
+		start := index.LineOf(edit.Start)
+		end := index.LineOf(edit.End)
 		if edit.End == len(content) && len(content) > 0 && content[len(content)-1] != '\n' {
 			end++ // EOF counts as an implicit newline
 		}
@@ -134,12 +153,12 @@ func toUnified[S text.String](fromName, toName string, content S, edits []Edit[S
 			//direct extension
 		case h != nil && start <= last+gap:
 			//within range of previous lines, add the joiners
-			addEqualLines(h, lines, last, start)
+			addEqualLines(h, index, last, start)
 		default:
 			//need to start a new hunk
 			if h != nil {
 				// add the edge to the previous hunk
-				addEqualLines(h, lines, last, last+edge)
+				addEqualLines(h, index, last, last+edge)
 				u.Hunks = append(u.Hunks, h)
 			}
 			toLine += start - last
@@ -148,13 +167,13 @@ func toUnified[S text.String](fromName, toName string, content S, edits []Edit[S
 				ToLine:   toLine + 1,
 			}
 			// add the edge to the new hunk
-			delta := addEqualLines(h, lines, start-edge, start)
+			delta := addEqualLines(h, index, start-edge, start)
 			h.FromLine -= delta
 			h.ToLine -= delta
 		}
 		last = start
 		for i := start; i < end; i++ {
-			h.Lines = append(h.Lines, line{Kind: Delete, Content: string(lines[i])})
+			h.Lines = append(h.Lines, line{Kind: Delete, Content: string(index.Line(i))})
 			last++
 		}
 		if len(edit.New) != 0 {
@@ -166,7 +185,7 @@ func toUnified[S text.String](fromName, toName string, content S, edits []Edit[S
 	}
 	if h != nil {
 		// add the edge to the final hunk
-		addEqualLines(h, lines, last, last+edge)
+		addEqualLines(h, index, last, last+edge)
 		u.Hunks = append(u.Hunks, h)
 	}
 	return u, nil
@@ -181,7 +200,10 @@ func splitLines[S text.String](t S) []S {
 }
 
 func lineOffsets[S text.String](lines []S) []int {
-	lineOffsets := make([]int, 0, len(lines)+1)
+	return appendLineOffsets(make([]int, 0, len(lines)+1), lines)
+}
+
+func appendLineOffsets[S text.String](lineOffsets []int, lines []S) []int {
 	total := 0
 	for i := range lines {
 		lineOffsets = append(lineOffsets, total)
@@ -191,16 +213,16 @@ func lineOffsets[S text.String](lines []S) []int {
 	return lineOffsets
 }
 
-func addEqualLines[S text.String](h *hunk, lines []S, start, end int) int {
+func addEqualLines[S text.String](h *hunk, index *LineIndex[S], start, end int) int {
 	delta := 0
 	for i := start; i < end; i++ {
 		if i < 0 {
 			continue
 		}
-		if i >= len(lines) {
+		if i >= index.LineCount() {
 			return delta
 		}
-		h.Lines = append(h.Lines, line{Kind: Equal, Content: string(lines[i])})
+		h.Lines = append(h.Lines, line{Kind: Equal, Content: string(index.Line(i))})
 		delta++
 	}
 	return delta
@@ -231,14 +253,17 @@ func (u unified) String() string {
 		fmt.Fprint(b, "@@")
 		if fromCount > 1 {
 			fmt.Fprintf(b, " -%d,%d", hunk.FromLine, fromCount)
-		} else if hunk.FromLine == 1 && fromCount == 0 {
-			// Match odd GNU diff -u behavior adding to empty file.
-			fmt.Fprintf(b, " -0,0")
+		} else if fromCount == 0 {
+			// Match GNU diff -u, which numbers an empty range by the
+			// line that precedes it.
+			fmt.Fprintf(b, " -%d,0", hunk.FromLine-1)
 		} else {
 			fmt.Fprintf(b, " -%d", hunk.FromLine)
 		}
 		if toCount > 1 {
 			fmt.Fprintf(b, " +%d,%d", hunk.ToLine, toCount)
+		} else if toCount == 0 {
+			fmt.Fprintf(b, " +%d,0", hunk.ToLine-1)
 		} else {
 			fmt.Fprintf(b, " +%d", hunk.ToLine)
 		}
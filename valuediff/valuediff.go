@@ -0,0 +1,447 @@
+// Package valuediff compares arbitrary Go values and reports their
+// differences, for use in test failure messages.
+//
+// Values are walked with reflection. The elements of slices and arrays are
+// aligned by their longest common subsequence, so that an inserted element
+// is reported once rather than as a change to every element that follows
+// it. Each difference is reported with the path at which it occurs:
+//
+//	.Users[1].Name:
+//		- "alice"
+//		+ "bob"
+//	.Tags[2]: (inserted)
+//		+ "new"
+//
+// Lines prefixed with "-" show the first value and lines prefixed with "+"
+// the second, so the conventional call is Diff(want, got, opts).
+package valuediff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/diff/lcs"
+)
+
+// Options controls how values are compared.
+type Options struct {
+	// IgnoreFields names struct fields to skip. An entry of the form
+	// "T.F" skips the field F of structs whose type is named T, and an
+	// entry without a dot skips fields of that name in any struct.
+	IgnoreFields []string
+	// IgnoreUnexported skips the unexported fields of structs.
+	IgnoreUnexported bool
+	// EquateEmpty treats nil slices and maps as equal to empty ones.
+	EquateEmpty bool
+	// Comparers decide the equality of values of particular types, which
+	// are then not walked. Comparers apply only to values that can be
+	// obtained without using unexported fields.
+	Comparers []Comparer
+}
+
+// A Comparer decides whether two values of some type are equal.
+type Comparer struct {
+	typ   reflect.Type
+	equal func(x, y reflect.Value) bool
+}
+
+// NewComparer returns a Comparer for values of type T.
+func NewComparer[T any](equal func(x, y T) bool) Comparer {
+	return Comparer{
+		typ: reflect.TypeOf((*T)(nil)).Elem(),
+		equal: func(x, y reflect.Value) bool {
+			return equal(x.Interface().(T), y.Interface().(T))
+		},
+	}
+}
+
+// Equal reports whether x and y are equal. Without options, it agrees with
+// reflect.DeepEqual.
+func Equal(x, y any, opts *Options) bool {
+	return newDiffer(opts).equal(reflect.ValueOf(x), reflect.ValueOf(y))
+}
+
+// Diff returns a report of the differences between x and y, or "" if they
+// are equal.
+func Diff(x, y any, opts *Options) string {
+	d := newDiffer(opts)
+	d.diff("", reflect.ValueOf(x), reflect.ValueOf(y))
+	return d.report.String()
+}
+
+// A differ compares values and accumulates a report of their differences.
+type differ struct {
+	opts    Options
+	ignore  map[string]bool
+	visited map[visit]bool
+	report  strings.Builder
+}
+
+// A visit is a pair of pointers, maps or slices that are being compared,
+// which is used to detect cycles.
+type visit struct {
+	x, y   uintptr
+	xn, yn int // the lengths of slices, which may share an array
+	typ    reflect.Type
+}
+
+// newVisit returns the visit of x and y, which are non-nil pointers, maps or
+// slices of the same type.
+func newVisit(x, y reflect.Value) visit {
+	v := visit{x: uintptr(x.UnsafePointer()), y: uintptr(y.UnsafePointer()), typ: x.Type()}
+	if x.Kind() == reflect.Slice {
+		v.xn, v.yn = x.Len(), y.Len()
+	}
+	return v
+}
+
+func newDiffer(opts *Options) *differ {
+	d := &differ{ignore: map[string]bool{}, visited: map[visit]bool{}}
+	if opts != nil {
+		d.opts = *opts
+	}
+	for _, f := range d.opts.IgnoreFields {
+		d.ignore[f] = true
+	}
+	return d
+}
+
+// skip reports whether the ith field of the struct type t is ignored.
+func (d *differ) skip(t reflect.Type, i int) bool {
+	f := t.Field(i)
+	return d.opts.IgnoreUnexported && !f.IsExported() || d.ignore[f.Name] || d.ignore[t.Name()+"."+f.Name]
+}
+
+// comparer returns the comparer for values of type t, if any.
+func (d *differ) comparer(t reflect.Type) func(x, y reflect.Value) bool {
+	for _, c := range d.opts.Comparers {
+		if c.typ == t {
+			return c.equal
+		}
+	}
+	return nil
+}
+
+// empty reports whether v is a slice or map that EquateEmpty makes equal to
+// nil.
+func (d *differ) empty(v reflect.Value) bool {
+	return d.opts.EquateEmpty && (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0
+}
+
+// equal reports whether x and y are equal.
+func (d *differ) equal(x, y reflect.Value) bool {
+	if !x.IsValid() || !y.IsValid() {
+		return x.IsValid() == y.IsValid()
+	}
+	if x.Type() != y.Type() {
+		return false
+	}
+	if eq := d.comparer(x.Type()); eq != nil && x.CanInterface() && y.CanInterface() {
+		return eq(x, y)
+	}
+	if d.empty(x) && d.empty(y) {
+		return true
+	}
+
+	switch x.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		if x.Kind() != reflect.Slice && x.UnsafePointer() == y.UnsafePointer() {
+			return true
+		}
+		v := newVisit(x, y)
+		if d.visited[v] {
+			return true // assume equality while comparing a cycle
+		}
+		d.visited[v] = true
+		defer delete(d.visited, v)
+	}
+
+	switch x.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		return d.equal(x.Elem(), y.Elem())
+	case reflect.Struct:
+		for i := 0; i < x.NumField(); i++ {
+			if !d.skip(x.Type(), i) && !d.equal(x.Field(i), y.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !d.equal(x.Index(i), y.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for _, k := range x.MapKeys() {
+			yv := y.MapIndex(k)
+			if !yv.IsValid() || !d.equal(x.MapIndex(k), yv) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		return x.IsNil() && y.IsNil()
+	case reflect.Bool:
+		return x.Bool() == y.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return x.Int() == y.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return x.Uint() == y.Uint()
+	case reflect.Float32, reflect.Float64:
+		return x.Float() == y.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return x.Complex() == y.Complex()
+	case reflect.String:
+		return x.String() == y.String()
+	case reflect.Chan, reflect.UnsafePointer:
+		return x.Pointer() == y.Pointer()
+	}
+	panic("unreachable: " + x.Kind().String())
+}
+
+// elements adapts the elements of a slice or array to lcs.DiffAnySlices.
+type elements struct {
+	d *differ
+}
+
+func (e elements) Equal(x, y reflect.Value) bool { return e.d.equal(x, y) }
+
+func values(v reflect.Value) []reflect.Value {
+	vs := make([]reflect.Value, v.Len())
+	for i := range vs {
+		vs[i] = v.Index(i)
+	}
+	return vs
+}
+
+// diff reports the differences between the values x and y at path.
+func (d *differ) diff(path string, x, y reflect.Value) {
+	if d.equal(x, y) {
+		return
+	}
+	if !x.IsValid() || !y.IsValid() || x.Type() != y.Type() || d.comparer(x.Type()) != nil {
+		d.changed(path, "", x, y)
+		return
+	}
+
+	switch x.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if !x.IsNil() && !y.IsNil() {
+			// Report the differences of a cycle once.
+			v := newVisit(x, y)
+			if d.visited[v] {
+				return
+			}
+			d.visited[v] = true
+			defer delete(d.visited, v)
+		}
+	}
+
+	switch x.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if x.IsNil() || y.IsNil() || x.Elem().Type() != y.Elem().Type() {
+			break
+		}
+		d.diff(path, x.Elem(), y.Elem())
+		return
+
+	case reflect.Struct:
+		for i := 0; i < x.NumField(); i++ {
+			if !d.skip(x.Type(), i) {
+				d.diff(path+"."+x.Type().Field(i).Name, x.Field(i), y.Field(i))
+			}
+		}
+		return
+
+	case reflect.Slice, reflect.Array:
+		if x.Kind() == reflect.Slice && (x.IsNil() || y.IsNil()) {
+			break
+		}
+		xs, ys := values(x), values(y)
+		for _, df := range lcs.DiffAnySlices(xs, ys, elements{d}) {
+			// Report elements that take each other's places as changed,
+			// and any others as removed or inserted.
+			i, j := df.Start, df.ReplStart
+			for ; i < df.End && j < df.ReplEnd; i, j = i+1, j+1 {
+				p := fmt.Sprintf("%s[%d]", path, i)
+				if i != j {
+					p = fmt.Sprintf("%s[%d->%d]", path, i, j)
+				}
+				d.diff(p, xs[i], ys[j])
+			}
+			for ; i < df.End; i++ {
+				d.changed(fmt.Sprintf("%s[%d]", path, i), "removed", xs[i], reflect.Value{})
+			}
+			for ; j < df.ReplEnd; j++ {
+				d.changed(fmt.Sprintf("%s[%d]", path, j), "inserted", reflect.Value{}, ys[j])
+			}
+		}
+		return
+
+	case reflect.Map:
+		if x.IsNil() || y.IsNil() {
+			break
+		}
+		for _, k := range sortedKeys(x, y) {
+			p := fmt.Sprintf("%s[%s]", path, format(k, 0))
+			xv, yv := x.MapIndex(k), y.MapIndex(k)
+			switch {
+			case !yv.IsValid():
+				d.changed(p, "removed", xv, yv)
+			case !xv.IsValid():
+				d.changed(p, "added", xv, yv)
+			default:
+				d.diff(p, xv, yv)
+			}
+		}
+		return
+	}
+	d.changed(path, "", x, y)
+}
+
+// changed reports a difference at path. A note, if any, follows the path,
+// and an invalid value is omitted.
+func (d *differ) changed(path, note string, x, y reflect.Value) {
+	if path == "" {
+		path = "(root)"
+	}
+	d.report.WriteString(path)
+	d.report.WriteByte(':')
+	if note != "" {
+		fmt.Fprintf(&d.report, " (%s)", note)
+	}
+	d.report.WriteByte('\n')
+	if x.IsValid() || note == "" {
+		fmt.Fprintf(&d.report, "\t- %s\n", format(x, 0))
+	}
+	if y.IsValid() || note == "" {
+		fmt.Fprintf(&d.report, "\t+ %s\n", format(y, 0))
+	}
+}
+
+// sortedKeys returns the union of the keys of two maps of the same type, in
+// the order of their formatted values.
+func sortedKeys(x, y reflect.Value) []reflect.Value {
+	keys := x.MapKeys()
+	for _, k := range y.MapKeys() {
+		if !x.MapIndex(k).IsValid() {
+			keys = append(keys, k)
+		}
+	}
+	formatted := make([]string, len(keys))
+	for i, k := range keys {
+		formatted[i] = format(k, 0)
+	}
+	sort.Sort(byFormat{keys, formatted})
+	return keys
+}
+
+type byFormat struct {
+	keys      []reflect.Value
+	formatted []string
+}
+
+func (b byFormat) Len() int           { return len(b.keys) }
+func (b byFormat) Less(i, j int) bool { return b.formatted[i] < b.formatted[j] }
+func (b byFormat) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.formatted[i], b.formatted[j] = b.formatted[j], b.formatted[i]
+}
+
+// maxDepth limits the nesting of formatted values, which also protects
+// against cycles.
+const maxDepth = 8
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// format formats a value on a single line, in a Go-like syntax.
+func format(v reflect.Value, depth int) string {
+	if !v.IsValid() {
+		return "<invalid>"
+	}
+	if depth > maxDepth {
+		return "..."
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return "nil"
+		}
+	}
+	if v.Kind() != reflect.Interface && v.Type().Implements(stringerType) && v.CanInterface() {
+		return v.Interface().(fmt.Stringer).String()
+	}
+
+	var b strings.Builder
+	switch v.Kind() {
+	case reflect.Pointer:
+		return "&" + format(v.Elem(), depth+1)
+	case reflect.Interface:
+		return format(v.Elem(), depth)
+	case reflect.Struct:
+		b.WriteString(v.Type().String())
+		b.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s: %s", v.Type().Field(i).Name, format(v.Field(i), depth+1))
+		}
+		b.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s(%q)", v.Type(), v.Bytes())
+		}
+		b.WriteString(v.Type().String())
+		b.WriteByte('{')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(format(v.Index(i), depth+1))
+		}
+		b.WriteByte('}')
+	case reflect.Map:
+		b.WriteString(v.Type().String())
+		b.WriteByte('{')
+		for i, k := range sortedKeys(v, v) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s: %s", format(k, depth+1), format(v.MapIndex(k), depth+1))
+		}
+		b.WriteByte('}')
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Complex())
+	default:
+		return fmt.Sprintf("%s(%#x)", v.Type(), v.Pointer())
+	}
+	return b.String()
+}
@@ -0,0 +1,178 @@
+package valuediff_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/diff/valuediff"
+)
+
+type user struct {
+	Name    string
+	Age     int
+	Tags    []string
+	Friends []*user
+	Attrs   map[string]int
+	secret  string
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		x, y any
+		opts *valuediff.Options
+		want string
+	}{
+		{
+			name: "equal",
+			x:    user{Name: "a", Tags: []string{"x"}}, y: user{Name: "a", Tags: []string{"x"}},
+		},
+		{
+			name: "scalar",
+			x:    1, y: 2,
+			want: "(root):\n\t- 1\n\t+ 2\n",
+		},
+		{
+			name: "different types",
+			x:    1, y: "1",
+			want: "(root):\n\t- 1\n\t+ \"1\"\n",
+		},
+		{
+			name: "fields",
+			x:    user{Name: "alice", Age: 30}, y: user{Name: "bob", Age: 30},
+			want: ".Name:\n\t- \"alice\"\n\t+ \"bob\"\n",
+		},
+		{
+			name: "insertion does not cascade",
+			x:    []int{1, 2, 3, 4}, y: []int{1, 9, 2, 3, 4},
+			want: "[1]: (inserted)\n\t+ 9\n",
+		},
+		{
+			name: "removal and change",
+			x:    []string{"a", "b", "c", "d"}, y: []string{"a", "c", "e"},
+			want: "[1]: (removed)\n\t- \"b\"\n[3->2]:\n\t- \"d\"\n\t+ \"e\"\n",
+		},
+		{
+			name: "nested",
+			x:    &user{Friends: []*user{{Name: "a"}, {Name: "b", Age: 1}}},
+			y:    &user{Friends: []*user{{Name: "a"}, {Name: "b", Age: 2}}},
+			want: ".Friends[1].Age:\n\t- 1\n\t+ 2\n",
+		},
+		{
+			name: "maps",
+			x:    map[string]int{"a": 1, "b": 2, "c": 3}, y: map[string]int{"b": 20, "c": 3, "d": 4},
+			want: "[\"a\"]: (removed)\n\t- 1\n[\"b\"]:\n\t- 2\n\t+ 20\n[\"d\"]: (added)\n\t+ 4\n",
+		},
+		{
+			name: "nil pointer",
+			x:    &user{}, y: (*user)(nil),
+			want: "(root):\n\t- &valuediff_test.user{Name: \"\", Age: 0, Tags: nil, Friends: nil, Attrs: nil, secret: \"\"}\n\t+ nil\n",
+		},
+		{
+			name: "nil and empty",
+			x:    user{Tags: []string{}}, y: user{},
+			want: ".Tags:\n\t- []string{}\n\t+ nil\n",
+		},
+		{
+			name: "equate empty",
+			x:    user{Tags: []string{}, Attrs: map[string]int{}}, y: user{},
+			opts: &valuediff.Options{EquateEmpty: true},
+		},
+		{
+			name: "unexported",
+			x:    user{secret: "a"}, y: user{secret: "b"},
+			want: ".secret:\n\t- \"a\"\n\t+ \"b\"\n",
+		},
+		{
+			name: "ignore unexported",
+			x:    user{secret: "a"}, y: user{secret: "b"},
+			opts: &valuediff.Options{IgnoreUnexported: true},
+		},
+		{
+			name: "ignore fields",
+			x:    user{Name: "a", Age: 1, Friends: []*user{{Age: 1}}}, y: user{Name: "b", Age: 2, Friends: []*user{{Age: 2}}},
+			opts: &valuediff.Options{IgnoreFields: []string{"user.Name", "Age"}},
+		},
+		{
+			name: "comparer",
+			x:    []float64{1, 2.0001}, y: []float64{1.00001, 2},
+			opts: &valuediff.Options{Comparers: []valuediff.Comparer{
+				valuediff.NewComparer(func(x, y float64) bool { return math.Abs(x-y) < 0.001 }),
+			}},
+		},
+		{
+			name: "bytes",
+			x:    []byte("ab"), y: []byte("ac"),
+			want: "[1]:\n\t- 98\n\t+ 99\n",
+		},
+		{
+			name: "interfaces",
+			x:    []any{1, "a"}, y: []any{1, 2},
+			want: "[1]:\n\t- \"a\"\n\t+ 2\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := valuediff.Diff(test.x, test.y, test.opts); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			if got := valuediff.Equal(test.x, test.y, test.opts); got != (test.want == "") {
+				t.Errorf("Equal = %v, want %v", got, test.want == "")
+			}
+		})
+	}
+}
+
+func TestEqualMatchesDeepEqual(t *testing.T) {
+	values := []any{
+		nil, 0, 1, "", "a", []int(nil), []int{}, []int{1}, map[string]int(nil), map[string]int{},
+		map[string]int{"a": 1}, &user{}, &user{Name: "a"}, user{Tags: []string{"a"}}, [2]int{1, 2},
+		math.NaN(), []any{nil}, []any{1},
+	}
+	for _, x := range values {
+		for _, y := range values {
+			if got, want := valuediff.Equal(x, y, nil), reflect.DeepEqual(x, y); got != want {
+				t.Errorf("Equal(%#v, %#v) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestCycles(t *testing.T) {
+	type node struct {
+		Value int
+		Next  *node
+	}
+	a := &node{Value: 1}
+	a.Next = a
+	b := &node{Value: 1}
+	b.Next = &node{Value: 1, Next: b}
+	if !valuediff.Equal(a, b, nil) {
+		t.Error("equal cycles compared unequal")
+	}
+	c := &node{Value: 1}
+	c.Next = &node{Value: 2, Next: c}
+	if got := valuediff.Diff(a, c, nil); !strings.HasPrefix(got, ".Next.Value:\n\t- 1\n\t+ 2\n") {
+		t.Errorf("got:\n%s", got)
+	}
+
+	// Slices and maps may also form cycles, through interfaces.
+	x := []any{nil, 1}
+	x[0] = x
+	y := []any{nil, 2}
+	y[0] = y
+	if valuediff.Equal(x, y, nil) {
+		t.Error("different slice cycles compared equal")
+	}
+	if got, want := valuediff.Diff(x, y, nil), "[1]:\n\t- 1\n\t+ 2\n"; got != want {
+		t.Errorf("slices: got:\n%s\nwant:\n%s", got, want)
+	}
+	m := map[string]any{"v": 1}
+	m["self"] = m
+	n := map[string]any{"v": 2}
+	n["self"] = n
+	if got, want := valuediff.Diff(m, n, nil), "[\"v\"]:\n\t- 1\n\t+ 2\n"; got != want {
+		t.Errorf("maps: got:\n%s\nwant:\n%s", got, want)
+	}
+}